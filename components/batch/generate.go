@@ -0,0 +1,194 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+)
+
+// formats generated puzzles can be written in
+var Formats = []string{"line", "grid", "json"}
+
+// which puzzles to generate and how to write them
+type GenerateOptions struct {
+	Count     int      // puzzles to write, 0 to keep going until cancelled
+	Size      int      // board size, see board.Sizes
+	Grades    []string // grades to keep, see board.Grades, any grade if empty
+	MinGivens int      // fewest given cells to keep, 0 for no limit
+	MaxGivens int      // most given cells to keep, 0 for no limit
+	Symmetry  string   // how givens are laid out, see board.Symmetries
+	Format    string   // how puzzles are written, see Formats
+	Jobs      int      // puzzles generated at once, 0 for one per CPU
+}
+
+// a generated puzzle written with --format json
+type generated struct {
+	Puzzle   string `json:"puzzle"`
+	Solution string `json:"solution"`
+	Grade    string `json:"grade"`
+	Givens   int    `json:"givens"`
+	Symmetry string `json:"symmetry"`
+}
+
+// a generated puzzle and its grade
+type gradedPuzzle struct {
+	board.Puzzle
+	grade string
+}
+
+/*
+Generates puzzles and writes the ones that match opts to w as they are found,
+until opts.Count are written or ctx is cancelled. Every worker keeps generating
+puzzles and throws away the ones with the wrong grade or number of givens, so
+options no puzzle can match run until cancelled. Generated puzzles always have
+one solution. Writes every puzzle on a line in the line and json formats, and
+as a puzzle file followed by a blank line in the grid format, see board.ParsePuzzle
+*/
+func Generate(ctx context.Context, w io.Writer, opts GenerateOptions) error {
+	if opts.Jobs <= 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	if len(opts.Grades) == 0 {
+		opts.Grades = board.Grades
+	}
+	keep := make(map[string]bool)
+	for _, grade := range opts.Grades {
+		keep[grade] = true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// workers get their seeds from one source so no two generate the same puzzle
+	var mu sync.Mutex
+	seeds := rand.New(rand.NewSource(time.Now().UnixNano()))
+	nextSeed := func() int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return seeds.Int63()
+	}
+
+	// the first worker to fail stops the others, its error is returned
+	var failed error
+	var failOnce sync.Once
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failed = err
+			cancel()
+		})
+	}
+
+	found := make(chan gradedPuzzle)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				seed := nextSeed()
+				p, err := board.GeneratePuzzle(seed, opts.Size, targetGivens(opts, seed), opts.Symmetry)
+				if err != nil {
+					fail(fmt.Errorf("seed %d: %w", seed, err))
+					return
+				}
+				givens := p.Givens()
+				if (opts.MinGivens > 0 && givens < opts.MinGivens) || (opts.MaxGivens > 0 && givens > opts.MaxGivens) {
+					continue
+				}
+				grade := p.Grade()
+				if !keep[grade] {
+					continue
+				}
+				select {
+				case found <- gradedPuzzle{p, grade}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	written := 0
+	var err error
+	for p := range found {
+		if err != nil || (opts.Count > 0 && written == opts.Count) {
+			continue
+		}
+		if err = writePuzzle(w, p, opts); err != nil {
+			cancel()
+			continue
+		}
+		written++
+		if written == opts.Count {
+			cancel()
+		}
+	}
+	if err == nil {
+		err = failed
+	}
+	return err
+}
+
+/*
+returns how many givens to aim for with the puzzle of seed. Within a givens
+range we pick one at random, so the whole range turns up, and otherwise aim for
+the givens of one of the grades we keep, like games of that difficulty do
+*/
+func targetGivens(opts GenerateOptions, seed int64) int {
+	rng := rand.New(rand.NewSource(seed))
+	if opts.MaxGivens > 0 {
+		return opts.MinGivens + rng.Intn(opts.MaxGivens-opts.MinGivens+1)
+	}
+
+	grade := opts.Grades[rng.Intn(len(opts.Grades))]
+	for mode, name := range board.Grades {
+		if name == grade {
+			return board.GivensFor(mode, opts.Size)
+		}
+	}
+	return board.GivensFor(0, opts.Size)
+}
+
+// writes p to w in the format of opts
+func writePuzzle(w io.Writer, p gradedPuzzle, opts GenerateOptions) error {
+	switch opts.Format {
+	case "json":
+		line, err := json.Marshal(generated{
+			Puzzle:   p.Line(),
+			Solution: p.Solution(),
+			Grade:    p.grade,
+			Givens:   p.Givens(),
+			Symmetry: p.Symmetry(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", line)
+		return err
+	case "grid":
+		_, err := fmt.Fprintf(w, "# %s, %d givens\n%s\n", p.grade, p.Givens(), p.Text())
+		return err
+	}
+	_, err := fmt.Fprintln(w, p.Line())
+	return err
+}
+
+// returns true if name is one of Formats
+func IsFormat(name string) bool {
+	for _, f := range Formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+/*
+Package batch solves and generates puzzles in bulk, for using the engine from
+scripts and data pipelines without a terminal. Both hand their work to a pool
+of workers, one per CPU unless told otherwise.
+
+Solve writes its results in the order the puzzles came in, one per line,
+either tab separated:
+
+	puzzles.txt:1	1	medium	2.1ms	483921657967345821251876493548132976729564138136798245372689514814253769695417382
+
+or as JSON with --json:
+
+	{"source":"puzzles.txt:1","puzzle":"4...","solutions":1,"solution":"4839...","grade":"medium","ms":2.1}
+
+A puzzle that can't be read or has no solution gets an error instead of a
+solution.
+*/
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+)
+
+// how puzzles are solved and their results written
+type SolveOptions struct {
+	Jobs  int  // puzzles solved at once, 0 for one per CPU
+	JSON  bool // write results as JSON instead of tab separated
+	Limit int  // stop counting solutions at this many, 2 is enough to tell unique puzzles apart
+}
+
+// what solving a puzzle found, see the package comment
+type Result struct {
+	Source    string  `json:"source"`
+	Puzzle    string  `json:"puzzle,omitempty"`
+	Solutions int     `json:"solutions"`
+	Solution  string  `json:"solution,omitempty"`
+	Grade     string  `json:"grade,omitempty"`
+	Millis    float64 `json:"ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// characters of a puzzle written on one line, a line with anything else starts a puzzle file
+const puzzleChars = ".0123456789ABCDEFGabcdefg "
+
+// a puzzle read from the input, or why it couldn't be read
+type task struct {
+	index  int
+	source string
+	puzzle board.Puzzle
+	err    error
+}
+
+/*
+Solves the puzzles in the files at paths, or in stdin if there are none or
+the path is -, and writes the results to w. A file whose first line only has
+the characters of a puzzle written on one line, see board.ParsePuzzleLine,
+holds a puzzle on every line, blank lines and lines starting with # are
+skipped. Any other file is a single puzzle file, see board.ParsePuzzle. Unique
+puzzles are graded. Returns an error once every result is written if any
+puzzle couldn't be read or had no solution
+*/
+func Solve(w io.Writer, paths []string, opts SolveOptions) error {
+	if opts.Jobs <= 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	if opts.Limit < 1 {
+		opts.Limit = 1
+	}
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	tasks := make(chan task)
+	go func() {
+		defer close(tasks)
+		index := 0
+		for _, path := range paths {
+			ReadPuzzles(path, func(source string, puzzle board.Puzzle, err error) {
+				tasks <- task{index: index, source: source, puzzle: puzzle, err: err}
+				index++
+			})
+		}
+	}()
+
+	results := make(chan indexedResult)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				results <- indexedResult{t.index, solvePuzzle(t, opts.Limit)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// results come in as workers finish, we hold on to the ones that are early
+	pending := make(map[int]Result)
+	next, unsolved := 0, 0
+	var writeErr error
+	for r := range results {
+		pending[r.index] = r.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if result.Error != "" {
+				unsolved++
+			}
+			// keep draining the workers after a write fails so they can finish
+			if writeErr == nil {
+				writeErr = writeResult(w, result, opts.JSON)
+			}
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if unsolved > 0 {
+		return fmt.Errorf("%d of %d puzzles could not be solved", unsolved, next)
+	}
+	return nil
+}
+
+// a result and the index of its puzzle in the input
+type indexedResult struct {
+	index  int
+	result Result
+}
+
+// solves the puzzle of t and grades it if it is unique
+func solvePuzzle(t task, limit int) Result {
+	r := Result{Source: t.source}
+	if t.err != nil {
+		r.Error = t.err.Error()
+		return r
+	}
+
+	start := time.Now()
+	r.Puzzle = t.puzzle.Line()
+	r.Solutions, r.Solution = t.puzzle.Solve(limit)
+	if r.Solutions == 1 {
+		r.Grade = t.puzzle.Grade()
+	}
+	r.Millis = float64(time.Since(start).Microseconds()) / 1000
+	if r.Solutions == 0 {
+		r.Error = "puzzle has no solution"
+	}
+	return r
+}
+
+// writes r as a line of text, or as JSON
+func writeResult(w io.Writer, r Result, asJSON bool) error {
+	if asJSON {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", line)
+		return err
+	}
+
+	if r.Error != "" {
+		_, err := fmt.Fprintf(w, "%s\terror: %s\n", r.Source, r.Error)
+		return err
+	}
+	grade := r.Grade
+	if grade == "" {
+		grade = "-"
+	}
+	_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%sms\t%s\n",
+		r.Source, r.Solutions, grade, strconv.FormatFloat(r.Millis, 'f', -1, 64), r.Solution)
+	return err
+}
+
+// reads the puzzles of the file at path, or stdin for -, in the formats Solve reads,
+// passing each to found with where it came from. A file that can't be read is passed on as an error
+func ReadPuzzles(path string, found func(source string, puzzle board.Puzzle, err error)) {
+	name, in := path, io.Reader(os.Stdin)
+	if path == "-" {
+		name = "stdin"
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			found(name, board.Puzzle{}, err)
+			return
+		}
+		defer f.Close()
+		in = f
+	}
+
+	r := bufio.NewReader(in)
+	// the first line that isn't blank or a comment tells the formats apart
+	var head strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		head.WriteString(line)
+		text := strings.TrimSpace(line)
+		if text != "" && !strings.HasPrefix(text, "#") {
+			if strings.Trim(text, puzzleChars) != "" {
+				// a puzzle file, read as one puzzle
+				puzzle, err := board.ReadPuzzle(io.MultiReader(strings.NewReader(head.String()), r))
+				found(name, puzzle, err)
+				return
+			}
+			break
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			found(name, board.Puzzle{}, err)
+			return
+		}
+	}
+
+	scanner := bufio.NewScanner(io.MultiReader(strings.NewReader(head.String()), r))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		puzzle, err := board.ParsePuzzleLine(text)
+		found(name+":"+strconv.Itoa(lineNum), puzzle, err)
+	}
+	if err := scanner.Err(); err != nil {
+		found(name, board.Puzzle{}, err)
+	}
+}
@@ -2,10 +2,12 @@ package board
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
 
 	"github.com/Alex-Merrill/sudoku-tui/components/inputs"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -20,24 +22,41 @@ type Model struct {
 	boardStates       []BoardState
 	currBoardStateIdx int
 	currBoardState    *BoardState
-	keyMap            inputs.KeyMap       // contains all inputs - uses bubbles/key to do fancy things for us
-	currCell          coordinate          // current cell player is on
-	selectedCells     map[coordinate]bool // keeps track of all selected cells
+	keyMap            inputs.KeyMap             // contains all inputs - uses bubbles/key to do fancy things for us
+	currCell          coordinate                // current cell player is on
+	selectedCells     map[coordinate]bool       // keeps track of all selected cells
+	settings          settings.Settings         // display options, owned by the app model
+	width, height     int                       // space available to draw the board in, 0 until the first window size
+	constraints       []Constraint              // rules of the puzzle, checked when the board is full
+	regionOf          [][]int                   // region index of every cell, used for drawing region borders
+	marks             map[gap]clueMark          // thermometer, arrow and kropki marks drawn between cells
+	thermos, arrows   [][]coordinate            // paths of the thermometers and arrows, shown above the board
+	cages             killerCages               // killer cages, used for drawing them
+	variants          []string                  // names of the variant rules, shown above the board
+	values            int                       // values go from 1 to values, the board is bigger for samurai puzzles
+	nav               navGraph                  // where the cursor moves from each cell, see inputHelpers.go
+	shared            bool                      // edits go through a server, see shared.go
+	playerCells       map[coordinate]playerCell // other players' cursors and selections on a shared board
+	recording         bool                      // send an Edited message for every edit, see shared.go
 }
 
 // using int8 for our board as our sudoku library gives us an array with int8's so less work xD
 type BoardState struct {
-	board [9][9]struct { // contains current game, answer key, and given cells
-		game      int8
-		answerKey int8
-		given     bool
-		pencils   map[int8]bool
-	}
+	board      [][]boardCell       // size x size cells, 9x9 for classic sudoku and 21x21 for samurai
 	wrongCells map[coordinate]bool // cells which contain the wrong number, shown upon puzzle completion
 	cellsLeft  int                 // keep track of this so we know when to display error highlighting
 	gameWon    bool
 }
 
+// contains current game, answer key, and given cells
+type boardCell struct {
+	game      int8
+	answerKey int8
+	given     bool
+	hole      bool // not on the board, i.e. between the grids of a samurai puzzle
+	pencils   sudoku.Candidates
+}
+
 type coordinate struct {
 	row, col int
 }
@@ -47,9 +66,10 @@ type GameWon struct{}
 
 /*
    BoardState method that will copy a board for the next board state
-   we need to initialize new maps for b.board.pencils and b.wrongCells
-   and copy the old maps to the new ones - we do this as to maintain different
-   wrongCells and different pencils states in each BoardState
+   we need to initialize new rows for b.board and a new map for b.wrongCells
+   and copy the old ones to the new ones - we do this as to maintain
+   different boards and wrongCells states in each BoardState. Pencil marks
+   are bitmasks, so copying the rows copies them too
 */
 func (b BoardState) copyBoard() BoardState {
 	// make new map for wrongCells
@@ -59,22 +79,19 @@ func (b BoardState) copyBoard() BoardState {
 	}
 	b.wrongCells = newWrongCells
 
-	// make new map for pencils in each cell
-	for i := 0; i < 9; i++ {
-		for j := 0; j < 9; j++ {
-			newPencils := make(map[int8]bool)
-			for k, v := range b.board[i][j].pencils {
-				newPencils[k] = v
-			}
-			b.board[i][j].pencils = newPencils
-		}
+	// make new rows for the board
+	newBoard := make([][]boardCell, len(b.board))
+	for i := range b.board {
+		newBoard[i] = append([]boardCell(nil), b.board[i]...)
 	}
+	b.board = newBoard
 
 	return b
 }
 
 // Using shift+[1-9] for penciling, thus we need to map
-// these characters to the proper number
+// these characters to the proper number. Values above 9
+// are pencilled with shift+[a-g]
 var pencilMap = map[string]int8{
 	"!": 1,
 	"@": 2,
@@ -85,47 +102,138 @@ var pencilMap = map[string]int8{
 	"&": 7,
 	"*": 8,
 	"(": 9,
+	"A": 10,
+	"B": 11,
+	"C": 12,
+	"D": 13,
+	"E": 14,
+	"F": 15,
+	"G": 16,
 }
 
-// Initializes board model
-func NewModel(mode int) Model {
-	/*
-	   Generates sudoku board
-	   Generate takes int 0-3 for easy, medium, hard, expert
-	   medium is broken in the package I am using, and I can't
-	   find a suitable library to replace it, so we are using
-	   0,2,3 for easy, medium, hard - this is defined in main.go
-	*/
+// returns the character for val, " " for an empty cell. Values above 9 are
+// written as letters, see sudoku.FormatValue
+func DigitString(val int8) string {
+	if s := sudoku.FormatValue(int(val)); s != "." {
+		return s
+	}
+	return " "
+}
+
+// returns the value of a digit character, letters in either case, or -1 if s isn't one
+func digitValue(s string) int8 {
+	val, ok := sudoku.ParseValue(s)
+	if !ok || val == 0 {
+		return -1
+	}
+	return int8(val)
+}
+
+/*
+describes the game NewModel sets up. Games are sent to other players, recorded
+and saved as these options in JSON, and generated again from them on the other
+side, so only generated puzzles can be written, Puzzle is left out
+*/
+type GameOptions struct {
+	Mode     int      `json:"mode,omitempty"`     // difficulty 0-3 for easy, medium, hard, expert, see main.go
+	Size     int      `json:"size,omitempty"`     // board size, see Sizes
+	Killer   bool     `json:"killer,omitempty"`   // generate a killer sudoku instead of a classic one
+	Samurai  bool     `json:"samurai,omitempty"`  // generate a samurai sudoku, five overlapping 9x9 grids
+	Variants []string `json:"variants,omitempty"` // variant rules to generate the puzzle with, see VariantNames
+	Symmetry string   `json:"symmetry,omitempty"` // lay the givens out with this symmetry, see Symmetries, empty for none
+	Puzzle   *Puzzle  `json:"-"`                  // play this puzzle instead of generating one
+	Seed     int64    `json:"seed,omitempty"`     // generate the same puzzle for the same seed and options, 0 for a random puzzle
+}
+
+// returns an error unless a puzzle can be generated from opts, for options read
+// back from JSON. Options with a Puzzle are played as they are and always pass
+func (opts GameOptions) Validate() error {
+	if opts.Puzzle != nil {
+		return nil
+	}
+	if opts.Mode < 0 || opts.Mode > 3 {
+		return fmt.Errorf("unknown difficulty %d", opts.Mode)
+	}
+	if !IsSize(opts.Size) {
+		return fmt.Errorf("unknown size %d", opts.Size)
+	}
+	for _, name := range opts.Variants {
+		if !IsVariant(name) {
+			return fmt.Errorf("unknown variant %q", name)
+		}
+	}
+	if opts.Symmetry != "" && !IsSymmetry(opts.Symmetry) {
+		return fmt.Errorf("unknown symmetry %q", opts.Symmetry)
+	}
+	return nil
+}
+
+/*
+   Generates classic sudoku board
+   Generate takes int 0-3 for easy, medium, hard, expert
+   medium is broken in the package I am using, and I can't
+   find a suitable library to replace it, so we are using
+   0,2,3 for easy, medium, hard - this is defined in main.go
+*/
+func generateClassic(mode int) (Puzzle, error) {
 	sudoku, err := generator.Generate(mode)
+	if err != nil {
+		return Puzzle{}, fmt.Errorf("%w: %v", ErrGenerate, err)
+	}
 	game, answerKey := sudoku.Puzzle(), sudoku.Answer()
 
+	p := Puzzle{size: 9, game: newGrid(9), answerKey: newGrid(9)}
+	for i := 0; i < 9; i++ {
+		for j := 0; j < 9; j++ {
+			p.game[i][j] = game[(i*9)+j]
+			p.answerKey[i][j] = answerKey[(i*9)+j]
+		}
+	}
+	return p, nil
+}
+
+// Initializes board model, or returns ErrGenerate if the puzzle of opts can't be generated
+func NewModel(opts GameOptions, s settings.Settings) (Model, error) {
+	var puzzle Puzzle
+	var err error
+	rng := newRand(opts.Seed)
+	switch {
+	case opts.Puzzle != nil:
+		puzzle = *opts.Puzzle
+	case opts.Samurai:
+		puzzle, err = generateSamurai(rng, opts.Mode, opts.Symmetry)
+	case opts.Killer:
+		puzzle, err = generateKiller(rng, opts.Mode, opts.Size, opts.Variants, opts.Symmetry)
+	// our sudoku library only makes classic 9x9 puzzles with givens anywhere, and can't be seeded
+	case len(opts.Variants) > 0 || opts.Size != 9 || opts.Seed != 0 || (opts.Symmetry != "" && opts.Symmetry != "none"):
+		puzzle, err = generateVariant(rng, opts.Mode, opts.Size, opts.Variants, opts.Symmetry)
+	default:
+		puzzle, err = generateClassic(opts.Mode)
+	}
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(0)
+		return Model{}, err
 	}
+	game, answerKey := puzzle.game, puzzle.answerKey
 
 	// populate board struct
 	// game is state of sudoku
 	// answerKey is solution
 	// given marks given tiles, the user cannot change them
-	var board [9][9]struct {
-		game      int8
-		answerKey int8
-		given     bool
-		pencils   map[int8]bool
-	}
+	// holes are never filled, they count as given
+	board := make([][]boardCell, len(game))
 	cellsLeft := 0
-	for i := 0; i < 9; i++ {
-		for j := 0; j < 9; j++ {
-			board[i][j].game = game[(i*9)+j]
-			board[i][j].answerKey = answerKey[(i*9)+j]
-			board[i][j].given = game[(i*9)+j] != -1
-			if given := game[(i*9)+j] != -1; given {
+	for i := range board {
+		board[i] = make([]boardCell, len(game))
+		for j := range board[i] {
+			board[i][j].game = game[i][j]
+			board[i][j].answerKey = answerKey[i][j]
+			board[i][j].given = game[i][j] != -1
+			board[i][j].hole = game[i][j] == hole
+			if given := game[i][j] != -1; given {
 				board[i][j].given = given
 			} else {
 				cellsLeft++
 			}
-			board[i][j].pencils = make(map[int8]bool)
 		}
 	}
 
@@ -149,7 +257,71 @@ func NewModel(mode int) Model {
 		keyMap:            inputs.Controls,
 		currCell:          startCell,
 		selectedCells:     selectedCells,
+		settings:          s,
+		constraints:       puzzle.constraints(),
+		regionOf:          regionIndex(puzzle.boxes(), len(game)),
+		marks:             clueMarks(puzzle),
+		thermos:           puzzle.thermos,
+		arrows:            puzzle.arrows,
+		cages:             newKillerCages(puzzle.cages),
+		variants:          puzzle.variants,
+		values:            puzzle.size,
+		nav:               newNavGraph(board),
+	}, nil
+}
+
+// replaces the board's display settings
+func (m *Model) SetSettings(s settings.Settings) {
+	m.settings = s
+}
+
+// sets how much room the board has to draw in, used to pick the layout
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// returns the layout the board is drawn in, resolving LayoutAuto to the
+// largest layout that fits in the space set by SetSize
+func (m Model) Layout() settings.Layout {
+	if m.settings.Layout != settings.LayoutAuto {
+		return m.settings.Layout
+	}
+	for _, l := range []settings.Layout{settings.LayoutFull, settings.LayoutMedium, settings.LayoutCompact} {
+		if m.fits(l) {
+			return l
+		}
 	}
+	return settings.LayoutCompact
+}
+
+// returns true if the board does not fit in its space in its current layout
+func (m Model) TooSmall() bool {
+	return !m.fits(m.Layout())
+}
+
+// returns width and height of the board drawn in its current layout
+func (m Model) Size() (int, int) {
+	return m.boardSize(m.Layout())
+}
+
+func (m Model) fits(l settings.Layout) bool {
+	// we don't know the window size yet
+	if m.width == 0 && m.height == 0 {
+		return true
+	}
+	w, h := m.boardSize(l)
+	return w <= m.width && h <= m.height
+}
+
+// returns width and height of the board drawn in layout l
+func (m Model) boardSize(l settings.Layout) (int, int) {
+	cells := len(m.currBoardState.board)
+	lm := getMetrics(l, m.values)
+	borderCols, borderRows := m.borderLines()
+	w := cells*lm.cellWidth + count(borderCols)*lm.borderWidth + lm.panelWidth
+	h := lm.headerHeight + cells*lm.cellHeight + count(borderRows)
+	return w, h
 }
 
 func (m Model) Init() tea.Cmd {
@@ -157,6 +329,7 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	var edited tea.Cmd
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
@@ -184,22 +357,42 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, inputs.Controls.ShiftRight):
 			m.cursorHighlightRight()
 
+		// shared boards are only changed by edits coming back from the server
+		case m.shared && key.Matches(msg, inputs.Controls.Number):
+			return m, m.sendEdit(EditSet, digitValue(msg.String()))
+
+		case m.shared && key.Matches(msg, inputs.Controls.PencilNumber):
+			return m, m.sendEdit(EditPencil, pencilMap[msg.String()])
+
+		case m.shared && key.Matches(msg, inputs.Controls.Delete):
+			return m, m.sendEdit(EditDelete, 0)
+
+		case m.shared && key.Matches(msg, inputs.Controls.Undo):
+			return m, m.sendEdit(EditUndo, 0)
+
+		case m.shared && key.Matches(msg, inputs.Controls.Redo):
+			return m, m.sendEdit(EditRedo, 0)
+
 		case key.Matches(msg, inputs.Controls.Number):
-			num, _ := strconv.Atoi(msg.String())
-			m.setCell(int8(num))
+			m.setCell(digitValue(msg.String()))
+			edited = m.recordEdit(EditSet, digitValue(msg.String()))
 
 		case key.Matches(msg, inputs.Controls.PencilNumber):
 			num := pencilMap[msg.String()]
 			m.setPencilCell(int8(num))
+			edited = m.recordEdit(EditPencil, int8(num))
 
 		case key.Matches(msg, inputs.Controls.Delete):
 			m.deleteCell()
+			edited = m.recordEdit(EditDelete, 0)
 
 		case key.Matches(msg, inputs.Controls.Undo):
 			m.UndoBoardAction()
+			edited = m.recordEdit(EditUndo, 0)
 
 		case key.Matches(msg, inputs.Controls.Redo):
 			m.RedoBoardAction()
+			edited = m.recordEdit(EditRedo, 0)
 
 		}
 	}
@@ -212,24 +405,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	*/
 	if m.currBoardState.cellsLeft == 0 && !m.currBoardState.gameWon {
 		if m.checkWon() {
-			return m, func() tea.Msg {
+			return m, tea.Batch(edited, func() tea.Msg {
 				return GameWon{}
-			}
+			})
 		}
 	}
 
-	return m, nil
+	return m, edited
 }
 
 func (m Model) View() string {
-	// converts board.game cell to string for draw
-	convertToString := func(num int8) string {
-		if num == -1 {
-			return " "
-		}
-		return fmt.Sprintf("%d", num)
-	}
-
 	// check if there is any wrong cells and add text to top of board
 	var err string
 	if len(m.currBoardState.wrongCells) > 1 {
@@ -240,39 +425,360 @@ func (m Model) View() string {
 		err = ""
 	}
 
-	// iterates through board to add to draw string
+	layout := m.Layout()
+
+	// show the variant rules being played
+	if len(m.variants) > 0 {
+		err = strings.TrimSpace(strings.Join(m.variants, " + ") + "  " + err)
+	}
+
+	// only full cells have room to draw cage sums, other layouts show the cursor's cage sum up top
+	if idx, ok := m.cages.cageOf[m.currCell]; ok && layout != settings.LayoutFull {
+		err = strings.TrimSpace(err + "  cage " + strconv.Itoa(m.cages.cages[idx].sum))
+	}
+
+	// say where the cursor's thermometers and arrows start, ascii marks don't show their direction
+	for _, path := range m.thermos {
+		if onPath(path, m.currCell) {
+			err = strings.TrimSpace(err + "  thermo from " + cellName(path[0]))
+		}
+	}
+	for _, path := range m.arrows {
+		if onPath(path, m.currCell) {
+			err = strings.TrimSpace(err + "  arrow from " + cellName(path[0]))
+		}
+	}
+
 	bLen := len(m.currBoardState.board)
-	boardString := err + "\n\n"
+	lm := getMetrics(layout, m.values)
+	header := err + strings.Repeat("\n", lm.headerHeight-1)
+
+	// value of the cursor cell, used for digit highlighting
+	currVal := m.currBoardState.board[m.currCell.row][m.currCell.col].game
+
+	// iterates through board to add to draw string
+	borderCols, borderRows := m.borderLines()
+	boardString := header
 	for i := 0; i < bLen; i++ {
 		rowString := ""
 		for j := 0; j < bLen; j++ {
 			_, cellWrong := m.currBoardState.wrongCells[coordinate{i, j}]
 			_, isSelected := m.selectedCells[coordinate{i, j}]
 			isCurrCell := m.currCell.row == i && m.currCell.col == j
+			val := m.currBoardState.board[i][j].game
+
+			// cells between the grids of a samurai puzzle are blank
+			if m.currBoardState.board[i][j].hole {
+				rowString = lipgloss.JoinHorizontal(lipgloss.Center, rowString, drawHoleCell(lm))
+				if borderCols[j] {
+					edge := m.regionBorder(coordinate{i, j}, coordinate{i, j + 1})
+					rowString = lipgloss.JoinHorizontal(lipgloss.Center, rowString, drawVerticalBorder(edge, clueMark{}, lm, m.settings))
+				}
+				continue
+			}
+
+			var matchPencil int8
+			if m.settings.HighlightDigits && currVal != -1 {
+				matchPencil = currVal
+			}
 
 			// add cell to row
-			cell := drawCell(cellWrong, isSelected, isCurrCell, m.currBoardState.board[i][j].given, convertToString(m.currBoardState.board[i][j].game), m.currBoardState.board[i][j].pencils)
+			cell := drawCell(cellState{
+				wrong:    cellWrong,
+				selected: isSelected,
+				current:  isCurrCell,
+				given:    m.currBoardState.board[i][j].given,
+				value:    DigitString(m.currBoardState.board[i][j].game),
+				pencils:  m.currBoardState.board[i][j].pencils,
+				size:     m.values,
+
+				peer:        m.settings.HighlightPeers && !isCurrCell && m.seesCursor(coordinate{i, j}),
+				sameDigit:   m.settings.HighlightDigits && !isCurrCell && val != -1 && val == currVal,
+				matchPencil: matchPencil,
+				cage:        m.cageEdges(coordinate{i, j}),
+
+				player:       m.playerCells[coordinate{i, j}].color,
+				playerCursor: m.playerCells[coordinate{i, j}].cursor,
+			}, m.settings, layout)
 			rowString = lipgloss.JoinHorizontal(lipgloss.Center, rowString, cell)
-			// if we are at column where box border goes, add border
-			if j == 2 || j == 5 {
-				rowString = lipgloss.JoinHorizontal(lipgloss.Center, rowString, drawBorder("vert", ""))
+			// if we are at column where region borders go, add border
+			if borderCols[j] {
+				edge := m.regionBorder(coordinate{i, j}, coordinate{i, j + 1})
+				mark := m.marks[gap{coordinate{i, j}, gapRight}]
+				rowString = lipgloss.JoinHorizontal(lipgloss.Center, rowString, drawVerticalBorder(edge, mark, lm, m.settings))
 			}
 		}
 
 		// add row to board
 		boardString = lipgloss.JoinVertical(lipgloss.Center, boardString, rowString)
 
-		// if we are at a row where box border goes, add border
-		if i == 2 || i == 5 {
-			boardString = lipgloss.JoinVertical(lipgloss.Center, boardString, drawBorder("hor", rowString))
+		// if we are at a row where region borders go, add border
+		if borderRows[i] {
+			boardString = lipgloss.JoinVertical(lipgloss.Center, boardString, m.drawBorderRow(i, borderCols, layout))
 		}
 	}
 
+	// compact cells have no room for pencil marks, show the cursor cell's in a panel
+	if layout == settings.LayoutCompact {
+		panel := strings.Repeat("\n", lm.headerHeight) + drawPencilPanel(m.currBoardState.board[m.currCell.row][m.currCell.col].pencils, m.values, m.settings)
+		boardString = lipgloss.JoinHorizontal(lipgloss.Top, boardString, panel)
+	}
+
 	return boardString
 }
 
+// returns the region index of every cell of a size x size board, -1 for cells in no region
+func regionIndex(regions [][]coordinate, size int) [][]int {
+	regionOf := make([][]int, size)
+	for i := range regionOf {
+		regionOf[i] = make([]int, size)
+		for j := range regionOf[i] {
+			regionOf[i][j] = -1
+		}
+	}
+	for idx, region := range regions {
+		for _, cell := range region {
+			regionOf[cell.row][cell.col] = idx
+		}
+	}
+	return regionOf
+}
+
+// returns true if a and b are in different regions, cells off the board are in no region
+func (m Model) regionBorder(a, b coordinate) bool {
+	size := len(m.regionOf)
+	onBoard := func(c coordinate) bool {
+		return c.row >= 0 && c.row < size && c.col >= 0 && c.col < size
+	}
+	if !onBoard(a) || !onBoard(b) {
+		return true
+	}
+	return m.regionOf[a.row][a.col] != m.regionOf[b.row][b.col]
+}
+
+/*
+   returns which columns and rows have a line of region borders after them.
+   A line goes after a column if a region border runs along it in any row,
+   for classic boxes that is after columns 2 and 5, and the same for rows.
+   Jigsaw regions usually need a line between every column and row.
+   Lines also go wherever there is a clue mark to draw between cells
+*/
+func (m Model) borderLines() ([]bool, []bool) {
+	size := len(m.regionOf)
+	cols, rows := make([]bool, size), make([]bool, size)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size-1; j++ {
+			if m.regionBorder(coordinate{i, j}, coordinate{i, j + 1}) {
+				cols[j] = true
+			}
+			if m.regionBorder(coordinate{j, i}, coordinate{j + 1, i}) {
+				rows[j] = true
+			}
+		}
+	}
+	for g := range m.marks {
+		if g.kind == gapRight || g.kind == gapJoint {
+			cols[g.cell.col] = true
+		}
+		if g.kind == gapDown || g.kind == gapJoint {
+			rows[g.cell.row] = true
+		}
+	}
+	return cols, rows
+}
+
+// draws the line of region borders under row i, with joints where it crosses the border columns
+func (m Model) drawBorderRow(i int, borderCols []bool, layout settings.Layout) string {
+	size := len(m.regionOf)
+	edges := make([]bool, size)
+	marks := make(map[int]clueMark)
+	joints := make(map[int]borderJoint)
+	jointMarks := make(map[int]clueMark)
+	for j := 0; j < size; j++ {
+		edges[j] = m.regionBorder(coordinate{i, j}, coordinate{i + 1, j})
+		if mark, ok := m.marks[gap{coordinate{i, j}, gapDown}]; ok {
+			marks[j] = mark
+		}
+		if mark, ok := m.marks[gap{coordinate{i, j}, gapJoint}]; ok {
+			jointMarks[j] = mark
+		}
+		if borderCols[j] {
+			joints[j] = borderJoint{
+				up:    m.regionBorder(coordinate{i, j}, coordinate{i, j + 1}),
+				down:  m.regionBorder(coordinate{i + 1, j}, coordinate{i + 1, j + 1}),
+				left:  edges[j],
+				right: m.regionBorder(coordinate{i, j + 1}, coordinate{i + 1, j + 1}),
+			}
+		}
+	}
+	return drawHorizontalBorder(edges, marks, joints, jointMarks, getMetrics(layout, m.values), m.settings)
+}
+
+// returns true if cell is on path
+func onPath(path []coordinate, cell coordinate) bool {
+	for _, c := range path {
+		if c == cell {
+			return true
+		}
+	}
+	return false
+}
+
+// returns cell written as r<row>c<col> counting from 1, like in puzzle files
+func cellName(cell coordinate) string {
+	return fmt.Sprintf("r%dc%d", cell.row+1, cell.col+1)
+}
+
+// returns how many of bs are true
+func count(bs []bool) int {
+	n := 0
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+// returns how many of each digit are still to be placed, indexed by digit.
+// Wrong values count as placed, overused digits go negative
+func (m Model) DigitsLeft() []int {
+	cells := 0
+	left := make([]int, m.values+1)
+	for i := range m.currBoardState.board {
+		for j := range m.currBoardState.board[i] {
+			cell := m.currBoardState.board[i][j]
+			if cell.hole {
+				continue
+			}
+			cells++
+			if cell.game != -1 {
+				left[cell.game]--
+			}
+		}
+	}
+	// a full board has every digit the same number of times
+	for num := 1; num <= m.values; num++ {
+		left[num] += cells / m.values
+	}
+	return left
+}
+
+// returns how many of the cells the player has to fill have a value, and how many there are
+func (m Model) Progress() (int, int) {
+	filled, total := 0, 0
+	for i := range m.currBoardState.board {
+		for j := range m.currBoardState.board[i] {
+			cell := m.currBoardState.board[i][j]
+			if cell.given {
+				continue
+			}
+			total++
+			if cell.game != -1 {
+				filled++
+			}
+		}
+	}
+	return filled, total
+}
+
+// the state of a board at one point of its undo history, for tests and tools that
+// check what keys did to it, see Snapshot
+type Snapshot struct {
+	Cells     [][]CellContents // every cell by row, Val is -1 for empty cells and 0 for holes
+	Givens    []Cell           // in reading order
+	Wrong     []Cell           // cells that don't match the answer key once the board is full, in reading order
+	CellsLeft int              // empty and wrong cells, the board is checked for a win at 0
+	Won       bool
+	Undo      int // index of the current board state in the undo history
+	History   int // board states in the undo history, undo moves towards 0 and redo towards History-1
+}
+
+// returns the current state of the board
+func (m Model) Snapshot() Snapshot {
+	s := Snapshot{
+		Cells:     make([][]CellContents, len(m.currBoardState.board)),
+		Wrong:     sortedCells(m.currBoardState.wrongCells),
+		CellsLeft: m.currBoardState.cellsLeft,
+		Won:       m.currBoardState.gameWon,
+		Undo:      m.currBoardStateIdx,
+		History:   len(m.boardStates),
+	}
+	for i, row := range m.currBoardState.board {
+		s.Cells[i] = make([]CellContents, len(row))
+		for j, bc := range row {
+			c := CellContents{Cell: Cell{Row: i, Col: j}, Val: bc.game}
+			bc.pencils.Iterate(func(p int) {
+				c.Pencils = append(c.Pencils, int8(p))
+			})
+			s.Cells[i][j] = c
+			if bc.given {
+				s.Givens = append(s.Givens, c.Cell)
+			}
+		}
+	}
+	return s
+}
+
+// returns how many values the player set don't match the answer key
+func (m Model) Mistakes() int {
+	mistakes := 0
+	for i := range m.currBoardState.board {
+		for j := range m.currBoardState.board[i] {
+			cell := m.currBoardState.board[i][j]
+			if !cell.given && cell.game != -1 && cell.game != cell.answerKey {
+				mistakes++
+			}
+		}
+	}
+	return mistakes
+}
+
+// returns which sides of cell border other cages, the sum is drawn in the
+// top left cell of each cage
+func (m Model) cageEdges(cell coordinate) cageEdges {
+	idx, ok := m.cages.cageOf[cell]
+	if !ok {
+		return cageEdges{}
+	}
+	differs := func(row, col int) bool {
+		other, ok := m.cages.cageOf[coordinate{row, col}]
+		return !ok || other != idx
+	}
+
+	edges := cageEdges{
+		caged:  true,
+		top:    differs(cell.row-1, cell.col),
+		bottom: differs(cell.row+1, cell.col),
+		left:   differs(cell.row, cell.col-1),
+		right:  differs(cell.row, cell.col+1),
+	}
+
+	anchor := m.cages.cages[idx].cells[0]
+	for _, c := range m.cages.cages[idx].cells {
+		if c.row < anchor.row || (c.row == anchor.row && c.col < anchor.col) {
+			anchor = c
+		}
+	}
+	if anchor == cell {
+		edges.sum = strconv.Itoa(m.cages.cages[idx].sum)
+	}
+	return edges
+}
+
+// returns true if cell is a peer of the cursor cell under any of the puzzle's rules,
+// for classic sudoku that is the same row, column, or box
+func (m Model) seesCursor(cell coordinate) bool {
+	return arePeers(m.constraints, m.currCell, cell)
+}
+
 // sets cell at all selected cells
 func (m *Model) setCell(num int8) {
+	// values that don't fit the board, i.e. 7 on a 6x6 board, do nothing
+	if num < 1 || int(num) > m.values {
+		return
+	}
+
 	// check if we need to make a new board state
 	somethingChanged := false
 	for k := range m.selectedCells {
@@ -319,12 +825,8 @@ func (m *Model) deleteCell() {
 		given := m.currBoardState.board[row][col].given
 		if !given && m.currBoardState.board[row][col].game != -1 { // delete cell value
 			somethingChanged = true
-		} else if !given { // delete pencil marks if no cell value
-			for i := 1; i < 10; i++ {
-				if m.currBoardState.board[row][col].pencils[int8(i)] {
-					somethingChanged = true
-				}
-			}
+		} else if !given && m.currBoardState.board[row][col].pencils != 0 { // delete pencil marks if no cell value
+			somethingChanged = true
 		}
 	}
 
@@ -341,19 +843,25 @@ func (m *Model) deleteCell() {
 		col := k.col
 		given := m.currBoardState.board[row][col].given
 		if !given && m.currBoardState.board[row][col].game != -1 { // delete cell value
+			// a wrong cell is already counted in cellsLeft, only a right one adds to it
+			if _, cellWrong := m.currBoardState.wrongCells[coordinate{row, col}]; !cellWrong {
+				m.currBoardState.cellsLeft++
+			}
 			m.currBoardState.board[row][col].game = -1
 			delete(m.currBoardState.wrongCells, coordinate{row, col})
-			m.currBoardState.cellsLeft++
 		} else if !given { // delete pencil marks if no cell value
-			for i := 1; i < 10; i++ {
-				m.currBoardState.board[row][col].pencils[int8(i)] = false
-			}
+			m.currBoardState.board[row][col].pencils = 0
 		}
 	}
 }
 
 // sets/removes pencil mark at all selected cells if cell is not given or value is not set
 func (m *Model) setPencilCell(num int8) {
+	// values that don't fit the board, i.e. 7 on a 6x6 board, do nothing
+	if num < 1 || int(num) > m.values {
+		return
+	}
+
 	// check if we need to make a new board state
 	somethingChanged := false
 	for k := range m.selectedCells {
@@ -380,45 +888,29 @@ func (m *Model) setPencilCell(num int8) {
 		given := m.currBoardState.board[row][col].given
 		set := m.currBoardState.board[row][col].game != -1
 		if !given && !set {
-			m.currBoardState.board[row][col].pencils[num] = !m.currBoardState.board[row][col].pencils[num]
+			pencils := &m.currBoardState.board[row][col].pencils
+			if pencils.Has(int(num)) {
+				*pencils = pencils.Remove(int(num))
+			} else {
+				*pencils = pencils.Add(int(num))
+			}
 		}
 	}
 }
 
-// updates pencil cells in given row/box/col based on new "num" in cell currCell
+// updates pencil cells ruled out by the puzzle's constraints based on new "num" in cell currCell,
+// for classic sudoku that is the given row/box/col
 // we only call this function from setCell
 func (m *Model) updatePencilCells(num int8, currCell coordinate) {
-	row := currCell.row
-	col := currCell.col
-	// row
-	for i := 0; i < 9; i++ {
-		coord := coordinate{row, i}
-		m.removePencilCell(num, coord)
-	}
-
-	// col
-	for i := 0; i < 9; i++ {
-		coord := coordinate{i, col}
-		m.removePencilCell(num, coord)
-	}
-
-	// box
-	// convert (row,col) to the box index (0,0 0,1 0,2 for top three boxes etc)
-	// then convert those coordinates to the actual coords of the top left cell in box
-	bRow := row / 3
-	bCol := col / 3
-	realRow := bRow * 3
-	realCol := bCol * 3
-	for i := realRow; i < realRow+3; i++ {
-		for j := realCol; j < realCol+3; j++ {
-			coord := coordinate{i, j}
-			m.removePencilCell(num, coord)
+	for _, c := range m.constraints {
+		for _, e := range c.Eliminations(currCell, num) {
+			m.removePencilCell(e.val, e.cell)
 		}
 	}
 }
 
 /*
-   takes a number 1-9 and a coordinate
+   takes a number 1-size and a coordinate
    if there is a pencil mark of number "num" at coordiante currCell
    and the cell is not given and a value is not set, removes pencil mark
    this is a helper function for updatePencilCells
@@ -427,10 +919,9 @@ func (m *Model) removePencilCell(num int8, currCell coordinate) {
 	row := currCell.row
 	col := currCell.col
 	given := m.currBoardState.board[row][col].given
-	pencilsContainsNum := m.currBoardState.board[row][col].pencils[num]
 	set := m.currBoardState.board[row][col].game != -1
-	if !given && !set && pencilsContainsNum {
-		m.currBoardState.board[row][col].pencils[num] = false
+	if !given && !set {
+		m.currBoardState.board[row][col].pencils = m.currBoardState.board[row][col].pencils.Remove(int(num))
 	}
 }
 
@@ -500,60 +991,7 @@ func (m *Model) checkWon() bool {
 	return won
 }
 
-/*
-   We can be a bit clever here and use row,col,box 2D arrays
-   with type bool
-   We can then check for correctness with one pass through the
-   board, as opposed to 3 (for every row, col, and box)
-   ex: if a 1 is placed at row 2 col 2, we will set
-   row[2][1] = true, col[2][1] = true, box[0][1] = true
-*/
+// the board is won when it is full and follows every rule of the puzzle
 func (m *Model) checkForWinManual() bool {
-	// [9][10] because there are 9 cells in each row/col/box
-	// but we have numbers 1-9, so [c][0] will never be used
-	var row, col, box [9][10]bool
-
-	sudokuLen := len(m.currBoardState.board)
-	for i := 0; i < sudokuLen; i++ {
-		for j := 0; j < sudokuLen; j++ {
-			val := m.currBoardState.board[i][j].game
-			if val != -1 {
-				// row check
-				if row[i][val] { // we have a dupe
-					return false
-				}
-				row[i][val] = true
-
-				// col check
-				if col[j][val] { // we have a dupe
-					return false
-				}
-				col[j][val] = true
-
-				// box check
-				/*
-				   convert i,j to boxRow/boxCol indices
-				   ie: (0,0)│(0,1)│(0,2)
-				       ─────┼─────┼─────
-				       (1,0)│(1,1)│(1,2)
-				       ─────┼─────┼─────
-				       (2,0)│(2,1)│(2,2)
-				   then convert to flat index boxIndx [0-8]
-				*/
-				bRow := i / 3
-				bCol := j / 3
-				boxIdx := bRow*3 + bCol
-				if box[boxIdx][val] { // we have a dupe
-					return false
-				}
-				box[boxIdx][val] = true
-
-			} else { // i dont think we can ever get here, but better safe than sorry
-				return false
-			}
-		}
-	}
-
-	// if we've made it here, there were no dupes, thus win
-	return true
+	return satisfied(m.constraints, m.currBoardState.grid())
 }
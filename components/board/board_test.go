@@ -0,0 +1,60 @@
+package board
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+)
+
+// returns a seeded game of size with every empty cell pencilled with its candidates,
+// the most a board state has to copy
+func pencilledModel(tb testing.TB, size int) Model {
+	tb.Helper()
+	p, err := GeneratePuzzle(1, size, GivensFor(1, size), "none")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	m, err := NewModel(GameOptions{Size: size, Puzzle: &p}, settings.Settings{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	candidates := p.Candidates()
+	for i := range m.currBoardState.board {
+		for j := range m.currBoardState.board[i] {
+			m.currBoardState.board[i][j].pencils = candidates[i][j]
+		}
+	}
+	return m
+}
+
+// every edit snapshots the board for undo, see makeNewBoardState
+func BenchmarkCopyBoard(b *testing.B) {
+	for _, size := range []int{9, 16} {
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			state := *pencilledModel(b, size).currBoardState
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				state = state.copyBoard()
+			}
+		})
+	}
+}
+
+// the candidates the givens leave every cell, what export pencils in with --candidates
+func BenchmarkCandidates(b *testing.B) {
+	for _, size := range []int{9, 16} {
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			p, err := GeneratePuzzle(1, size, GivensFor(1, size), "none")
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Candidates()
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package board
+
+// clues drawn on top of the grid: thermometers, arrows and kropki dots. Each kind is one
+// constraint covering every clue of that kind in the puzzle
+
+// where a cell sits on one of a constraint's paths
+type pathPosition struct {
+	path, idx int
+}
+
+// maps every cell on paths to its positions, a cell can be on more than one path
+func pathPositions(paths [][]coordinate) map[coordinate][]pathPosition {
+	at := make(map[coordinate][]pathPosition)
+	for p, path := range paths {
+		for i, cell := range path {
+			at[cell] = append(at[cell], pathPosition{p, i})
+		}
+	}
+	return at
+}
+
+// thermometer rule, values strictly increase from the bulb, the first cell of each path
+type thermometers struct {
+	size  int
+	paths [][]coordinate
+	at    map[coordinate][]pathPosition
+}
+
+func newThermometers(size int, paths [][]coordinate) thermometers {
+	return thermometers{size: size, paths: paths, at: pathPositions(paths)}
+}
+
+// values on a thermometer all differ, so the other cells of its thermometers are peers
+func (t thermometers) Peers(cell coordinate) []coordinate {
+	var peers []coordinate
+	for _, pos := range t.at[cell] {
+		for i, other := range t.paths[pos.path] {
+			if i != pos.idx {
+				peers = append(peers, other)
+			}
+		}
+	}
+	return peers
+}
+
+/*
+a value is valid if it leaves room for the cells around it. k cells up the
+thermometer from a value v the value must be at least v+k, and the whole
+thermometer has to fit between 1 and the largest value on the board
+*/
+func (t thermometers) Valid(g grid, cell coordinate) bool {
+	val := int(g[cell.row][cell.col])
+	if val == -1 {
+		return true
+	}
+	for _, pos := range t.at[cell] {
+		path := t.paths[pos.path]
+		if val < pos.idx+1 || val > t.size-(len(path)-1-pos.idx) {
+			return false
+		}
+		for i, other := range path {
+			otherVal := int(g[other.row][other.col])
+			if otherVal == -1 || i == pos.idx {
+				continue
+			}
+			if (i < pos.idx && val-otherVal < pos.idx-i) || (i > pos.idx && otherVal-val < i-pos.idx) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (t thermometers) Eliminations(cell coordinate, val int8) []candidate {
+	var eliminated []candidate
+	for _, pos := range t.at[cell] {
+		for i, other := range t.paths[pos.path] {
+			for v := int8(1); int(v) <= t.size; v++ {
+				if (i < pos.idx && int(val-v) < pos.idx-i) || (i > pos.idx && int(v-val) < i-pos.idx) {
+					eliminated = append(eliminated, candidate{other, v})
+				}
+			}
+		}
+	}
+	return eliminated
+}
+
+// arrow rule, the values along each arrow add up to the value in its circle, the first cell of each path
+type arrows struct {
+	size  int
+	paths [][]coordinate
+	at    map[coordinate][]pathPosition
+}
+
+func newArrows(size int, paths [][]coordinate) arrows {
+	return arrows{size: size, paths: paths, at: pathPositions(paths)}
+}
+
+// values along an arrow may repeat, and the circle is always bigger than any of them
+func (a arrows) Peers(cell coordinate) []coordinate {
+	return nil
+}
+
+/*
+a value is valid if its arrows can still add up to their circles. With k empty
+cells left on an arrow they add at least k and at most k times the largest value,
+and an arrow with an empty circle can't add up to more than the largest value
+*/
+func (a arrows) Valid(g grid, cell coordinate) bool {
+	if g[cell.row][cell.col] == -1 {
+		return true
+	}
+	maxVal := a.size
+	for _, pos := range a.at[cell] {
+		path := a.paths[pos.path]
+		circle := int(g[path[0].row][path[0].col])
+		total, empty := 0, 0
+		for _, other := range path[1:] {
+			if otherVal := g[other.row][other.col]; otherVal == -1 {
+				empty++
+			} else {
+				total += int(otherVal)
+			}
+		}
+		if circle == -1 && total+empty > maxVal {
+			return false
+		}
+		if circle != -1 && (total+empty > circle || total+empty*maxVal < circle) {
+			return false
+		}
+	}
+	return true
+}
+
+// the circle is at least the arrow value plus 1 for every other cell on the arrow,
+// and arrow values are at most the circle less 1 for every other cell
+func (a arrows) Eliminations(cell coordinate, val int8) []candidate {
+	var eliminated []candidate
+	for _, pos := range a.at[cell] {
+		path := a.paths[pos.path]
+		others := int8(len(path) - 2)
+		for v := int8(1); int(v) <= a.size; v++ {
+			if pos.idx == 0 {
+				for _, other := range path[1:] {
+					if v > val-others {
+						eliminated = append(eliminated, candidate{other, v})
+					}
+				}
+			} else if v < val+others {
+				eliminated = append(eliminated, candidate{path[0], v})
+			}
+		}
+	}
+	return eliminated
+}
+
+// a kropki dot between two orthogonally neighboring cells. A white dot means the values
+// are consecutive, a black dot means one value is double the other
+type kropkiDot struct {
+	a, b  coordinate
+	black bool
+}
+
+// returns true if values x and y fit the dot
+func (d kropkiDot) fits(x, y int8) bool {
+	if d.black {
+		return x == 2*y || y == 2*x
+	}
+	return x == y+1 || y == x+1
+}
+
+// kropki rule for all dots of a puzzle
+type kropkiDots struct {
+	size int
+	dots []kropkiDot
+	at   map[coordinate][]int // indexes into dots for every cell next to a dot
+}
+
+func newKropkiDots(size int, dots []kropkiDot) kropkiDots {
+	at := make(map[coordinate][]int)
+	for i, d := range dots {
+		at[d.a] = append(at[d.a], i)
+		at[d.b] = append(at[d.b], i)
+	}
+	return kropkiDots{size: size, dots: dots, at: at}
+}
+
+// values on either side of a dot always differ, and they already share a row or col
+func (k kropkiDots) Peers(cell coordinate) []coordinate {
+	return nil
+}
+
+func (k kropkiDots) Valid(g grid, cell coordinate) bool {
+	for _, idx := range k.at[cell] {
+		d := k.dots[idx]
+		x, y := g[d.a.row][d.a.col], g[d.b.row][d.b.col]
+		if x != -1 && y != -1 && !d.fits(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+func (k kropkiDots) Eliminations(cell coordinate, val int8) []candidate {
+	var eliminated []candidate
+	for _, idx := range k.at[cell] {
+		d := k.dots[idx]
+		other := d.a
+		if other == cell {
+			other = d.b
+		}
+		for v := int8(1); int(v) <= k.size; v++ {
+			if !d.fits(val, v) {
+				eliminated = append(eliminated, candidate{other, v})
+			}
+		}
+	}
+	return eliminated
+}
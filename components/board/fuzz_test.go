@@ -0,0 +1,202 @@
+package board
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// puzzles the board actions are played on, small so that fuzzed games fill them
+// up and get checked for a win
+var fuzzPuzzles = []string{
+	"21..342...3...1.",
+	"1.....4.3...6.......5...2.4.1.......",
+}
+
+// keys fuzzed games press, by action, see FuzzBoardActions
+var (
+	moveKeys   = []tea.KeyType{tea.KeyUp, tea.KeyDown, tea.KeyLeft, tea.KeyRight}
+	selectKeys = []tea.KeyType{tea.KeyShiftUp, tea.KeyShiftDown, tea.KeyShiftLeft, tea.KeyShiftRight}
+	valueKeys  = "123456789abcdefg"
+	pencilKeys = "!@#$%^&*(ABCDEFG"
+)
+
+// the most actions a fuzzed game plays, enough to fill in either puzzle. Longer inputs
+// are skipped, minimizing them takes the fuzzer minutes without finding anything
+// shorter games don't
+const maxFuzzActions = 128
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func fuzzModel(t *testing.T, line string) Model {
+	p, err := ParsePuzzleLine(line)
+	if err == nil {
+		p, err = ParsePuzzle(strings.NewReader(p.Text()))
+	}
+	if err != nil {
+		t.Fatalf("puzzle %q: %v", line, err)
+	}
+	m, err := NewModel(GameOptions{Size: p.Size(), Puzzle: &p}, settings.Settings{})
+	if err != nil {
+		t.Fatalf("puzzle %q: %v", line, err)
+	}
+	return m
+}
+
+/*
+plays a game of actions, one per byte: the top three bits pick the action and the
+rest its argument. Actions are moving the cursor, growing the selection, setting,
+pencilling and deleting the selected cells, undo, redo and restoring the cursor's
+cell the way a shared board does. After every action the board has to keep to its
+invariants, see checkInvariants. Games of more than maxFuzzActions are skipped
+*/
+func FuzzBoardActions(f *testing.F) {
+	f.Add(byte(0), []byte{0x43, 0x43, 0x41, 0x80, 0xa0, 0xc0})
+	f.Add(byte(0), []byte{0x23, 0x02, 0x60, 0x61, 0x40, 0x80, 0xa0, 0xa0, 0xc0})
+	// fills the 4x4 in with r3c4 wrong, then deletes it and fills it in right
+	f.Add(byte(0), []byte{
+		0x03, 0x03, 0x43, 0x03, 0x42, 0x01, 0x40, 0x01, 0x40, 0x02, 0x02, 0x02, 0x40, 0x03, 0x41,
+		0x01, 0x42, 0x02, 0x43, 0x03, 0x03, 0x03, 0x41, 0x00, 0x80, 0x43,
+	})
+	f.Add(byte(1), []byte{0x22, 0x21, 0x45, 0x65, 0xe3, 0x80, 0xa0, 0xe0})
+
+	f.Fuzz(func(t *testing.T, puzzle byte, actions []byte) {
+		if len(actions) > maxFuzzActions {
+			t.Skip()
+		}
+		m := fuzzModel(t, fuzzPuzzles[int(puzzle)%len(fuzzPuzzles)])
+		size := len(m.currBoardState.board)
+		start := m.Snapshot()
+		for i, a := range actions {
+			arg := int(a & 0x1f)
+			switch a >> 5 {
+			case 0:
+				m, _ = m.Update(tea.KeyMsg{Type: moveKeys[arg%len(moveKeys)]})
+			case 1:
+				m, _ = m.Update(tea.KeyMsg{Type: selectKeys[arg%len(selectKeys)]})
+			case 2:
+				m, _ = m.Update(runes(string(valueKeys[arg%len(valueKeys)])))
+			case 3:
+				m, _ = m.Update(runes(string(pencilKeys[arg%len(pencilKeys)])))
+			case 4:
+				m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+			case 5:
+				m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlZ})
+			case 6:
+				m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+			case 7:
+				cursor, _ := m.Cursor()
+				m.ApplyEdit(Edit{Op: EditRestore, Contents: []CellContents{
+					{Cell: cursor, Val: int8(arg%(size+1)) - 1, Pencils: []int8{int8(arg%size + 1)}},
+				}})
+				// the app passes every message on to the board, which checks for a win
+				m, _ = m.Update(nil)
+			}
+			checkInvariants(t, m, start, actions[:i+1])
+		}
+	})
+}
+
+/*
+fails the test unless the board of m keeps to its invariants: cellsLeft counts the
+empty and the wrong cells, the givens are those of start, and undoing then redoing
+the last board state gives the board back exactly
+*/
+func checkInvariants(t *testing.T, m Model, start Snapshot, actions []byte) {
+	t.Helper()
+	s := m.Snapshot()
+
+	empty := 0
+	for _, row := range s.Cells {
+		for _, c := range row {
+			if c.Val == -1 {
+				empty++
+			}
+		}
+	}
+	if s.CellsLeft != empty+len(s.Wrong) {
+		t.Fatalf("after %x: %d cells left, want %d empty and %d wrong", actions, s.CellsLeft, empty, len(s.Wrong))
+	}
+
+	if !reflect.DeepEqual(s.Givens, start.Givens) {
+		t.Fatalf("after %x: givens %v, want %v", actions, s.Givens, start.Givens)
+	}
+	for _, g := range start.Givens {
+		if got, want := s.Cells[g.Row][g.Col], start.Cells[g.Row][g.Col]; !got.Equal(want) {
+			t.Fatalf("after %x: given %s holds %d, want %d", actions, g, got.Val, want.Val)
+		}
+	}
+
+	if s.Undo > 0 {
+		undone, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlZ})
+		redone, _ := undone.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+		if got := redone.Snapshot(); !reflect.DeepEqual(got, s) {
+			t.Fatalf("after %x: undo then redo gives\n%+v\nwant\n%+v", actions, got, s)
+		}
+	}
+}
+
+// puzzles read from a line have to write the same line, and read back from the
+// file they write the same puzzle
+func FuzzParsePuzzleLine(f *testing.F) {
+	for _, line := range append(fuzzPuzzles,
+		"53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79",
+		strings.Repeat(".", 81),
+		"123",
+	) {
+		f.Add(line)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		p, err := ParsePuzzleLine(line)
+		if err != nil {
+			return
+		}
+		again, err := ParsePuzzleLine(p.Line())
+		if err != nil {
+			t.Fatalf("%q wrote line %q which doesn't read: %v", line, p.Line(), err)
+		}
+		if again.Line() != p.Line() {
+			t.Fatalf("%q wrote line %q, which writes %q", line, p.Line(), again.Line())
+		}
+		read, err := ReadPuzzle(strings.NewReader(p.Text()))
+		if err != nil {
+			t.Fatalf("%q wrote\n%s\nwhich doesn't read: %v", line, p.Text(), err)
+		}
+		if read.Line() != p.Line() {
+			t.Fatalf("%q reads back from its file as %q", line, read.Line())
+		}
+	})
+}
+
+// puzzle files that read have to write a file that reads as the same puzzle
+func FuzzReadPuzzle(f *testing.F) {
+	for _, text := range []string{
+		"size 4\ngrid\n21..\n342.\n..3.\n..1.\n",
+		"# killer\nsize 4\ngrid\n....\n....\n....\n....\ncage 3 r1c1 r1c2\ncage 7 r1c3 r1c4\n",
+		"size 6\ngrid\n1.....\n4.3...\n6.....\n..5...\n2.4.1.\n......\nsymmetry none\n",
+		"size 4\nregions\n1122\n1122\n3344\n3344\ngrid\n....\n....\n....\n....\n",
+		"grid\n" + strings.Repeat(".........\n", 9) + "variant diagonal\nthermo r1c1 r2c2 r3c3\narrow r5c5 r5c6\nkropki white r9c8 r9c9\n",
+	} {
+		f.Add(text)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		p, err := ReadPuzzle(strings.NewReader(text))
+		if err != nil {
+			return
+		}
+		read, err := ReadPuzzle(strings.NewReader(p.Text()))
+		if err != nil {
+			t.Fatalf("%q wrote\n%s\nwhich doesn't read: %v", text, p.Text(), err)
+		}
+		if read.Text() != p.Text() {
+			t.Fatalf("%q wrote\n%s\nwhich writes\n%s", text, p.Text(), read.Text())
+		}
+	})
+}
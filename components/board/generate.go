@@ -0,0 +1,121 @@
+package board
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// given cells we aim for in generated 9x9 variant puzzles, indexed by mode. We stop removing
+// givens at the target, or earlier if no more can go without losing the unique solution.
+// Other sizes aim for the same share of the board, see scaleToCells
+var variantGivens = []int{36, 30, 26, 22}
+
+const (
+	// guesses the solver may take to prove a 9x9 puzzle unique while removing givens
+	variantMaxNodes = 5000
+	// guesses the solver may take to fill a grid, some combinations of variants are very
+	// hard or impossible to fill
+	fillMaxNodes = 2000000
+)
+
+// returned when generating a puzzle fails, because no grid following its rules was
+// found within fillMaxNodes guesses or the generator library gave up
+var ErrGenerate = errors.New("could not generate a puzzle for these rules")
+
+// fills a random grid for the rules of p, or returns ErrGenerate if the rules can't be filled
+func fillPuzzle(p Puzzle, rng *rand.Rand) (grid, error) {
+	g := fillGrid(p.emptyGrid(), p.size, p.constraints(), rng, fillMaxNodes)
+	if g == nil {
+		return nil, ErrGenerate
+	}
+	return g, nil
+}
+
+// returns the random source for generating a puzzle. Puzzles generated from the same
+// nonzero seed are the same, a seed of 0 picks a random one
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// scales a count for a 9x9 board, like givens or solver guesses, to a board with cells cells
+func scaleToCells(n, cells int) int {
+	return n * cells / 81
+}
+
+/*
+Generates a puzzle of size for difficulty mode (0-3) with variant rules on top of the
+classic ones and givens laid out with symmetry. Our sudoku library only knows classic
+9x9 rules, so we fill a random grid that follows all the rules, then remove givens,
+see removeGivens
+*/
+func generateVariant(rng *rand.Rand, mode, size int, variantNames []string, symmetry string) (Puzzle, error) {
+	p := Puzzle{size: size, variants: variantNames, symmetry: symmetry}
+	answerKey, err := fillPuzzle(p, rng)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	p.answerKey = answerKey
+	p.game = p.answerKey.copyGrid()
+	removeGivens(&p, GivensFor(mode, size), rng)
+
+	return p, nil
+}
+
+/*
+Generates a classic puzzle of size from seed the way variant puzzles are, with
+at most givens given cells laid out with symmetry, see Symmetries. More stay
+when no more can go without losing the unique solution. The same seed and
+options generate the same puzzle. Returns ErrGenerate if no grid could be filled
+*/
+func GeneratePuzzle(seed int64, size, givens int, symmetry string) (Puzzle, error) {
+	rng := newRand(seed)
+	p := Puzzle{size: size, symmetry: symmetry}
+	answerKey, err := fillPuzzle(p, rng)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	p.answerKey = answerKey
+	p.game = p.answerKey.copyGrid()
+	removeGivens(&p, givens, rng)
+
+	return p, nil
+}
+
+// returns the given cells generated puzzles of size aim for at difficulty mode (0-3)
+func GivensFor(mode, size int) int {
+	return scaleToCells(variantGivens[mode], size*size)
+}
+
+// removes givens from the full game of p in random order until there are at most target,
+// putting them back if the puzzle would no longer have one solution. Givens go together
+// with the cells the puzzle's symmetry maps them to
+func removeGivens(p *Puzzle, target int, rng *rand.Rand) {
+	cells := p.cells()
+	constraints := p.constraints()
+	maxNodes := scaleToCells(variantMaxNodes, len(cells))
+	orbits := symmetryOrbits(cells, len(p.game), p.symmetry)
+
+	givens := len(cells)
+	for _, idx := range rng.Perm(len(orbits)) {
+		if givens <= target {
+			break
+		}
+		orbit := orbits[idx]
+		vals := make([]int8, len(orbit))
+		for i, cell := range orbit {
+			vals[i] = p.game[cell.row][cell.col]
+			p.game[cell.row][cell.col] = -1
+		}
+		if unique, _, _ := uniqueSolution(p.game, p.size, constraints, maxNodes); unique {
+			givens -= len(orbit)
+		} else {
+			for i, cell := range orbit {
+				p.game[cell.row][cell.col] = vals[i]
+			}
+		}
+	}
+}
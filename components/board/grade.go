@@ -0,0 +1,111 @@
+package board
+
+import (
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// difficulty grades of puzzles, easiest first, see Grade. They are the grades of
+// the sudoku package, which grades classic puzzles for us
+var Grades = func() []string {
+	grades := make([]string, len(sudoku.Grades))
+	for i, g := range sudoku.Grades {
+		grades[i] = string(g)
+	}
+	return grades
+}()
+
+/*
+Grades the puzzle by how a person would solve it. We fill in singles the way
+the solver narrows candidates: an easy puzzle falls to naked singles, cells
+with one candidate left, and a medium one also needs hidden singles, values
+with one place left in a row, column or box. When singles run out the rest is
+guessing, and the guesses the solver takes from there tell hard from expert.
+The puzzle should have a solution, one that has none is graded expert.
+Classic puzzles are graded by the sudoku package, the others with its singles
+and grades under the puzzle's own rules
+*/
+func (p Puzzle) Grade() string {
+	if g, ok := p.sudokuGrid(); ok {
+		grade, err := g.Grade()
+		if err != nil {
+			return Grades[len(Grades)-1]
+		}
+		return string(grade)
+	}
+
+	s := solver{values: p.size, constraints: p.constraints(), limit: 1}
+	g := p.game.copyGrid()
+	masks := s.initialMasks(g)
+	if masks == nil {
+		return Grades[len(Grades)-1]
+	}
+	size := len(g)
+	houses := p.houses()
+
+	singles := sudoku.Easy
+	for {
+		i, val, ok := sudoku.NakedSingle(masks)
+		if !ok {
+			i, val, ok = sudoku.HiddenSingle(masks, houses)
+			if ok {
+				singles = sudoku.Medium
+			}
+		}
+		if !ok {
+			break
+		}
+		cell := coordinate{i / size, i % size}
+		g[cell.row][cell.col] = int8(val)
+		if !validAt(s.constraints, g, cell) {
+			// a contradiction, only the search can tell there is no solution
+			g[cell.row][cell.col] = -1
+			break
+		}
+		s.eliminate(masks, size, cell, int8(val))
+		s.refinePeers(g, masks, cell)
+	}
+
+	s.searchMasks(g, masks)
+	if s.solutions != 1 {
+		return Grades[len(Grades)-1]
+	}
+	return string(sudoku.SearchGrade(singles, s.nodes, len(p.cells())))
+}
+
+// returns the rows, columns and boxes of the puzzle, the houses hidden singles are found
+// in, as indexes into the solver's candidate masks
+func (p Puzzle) houses() [][]int {
+	size := len(p.game)
+	var houses [][]coordinate
+	if p.samurai {
+		for _, g := range samuraiGrids {
+			for i := 0; i < 9; i++ {
+				var row, col []coordinate
+				for j := 0; j < 9; j++ {
+					row = append(row, coordinate{g.row + i, g.col + j})
+					col = append(col, coordinate{g.row + j, g.col + i})
+				}
+				houses = append(houses, row, col)
+			}
+		}
+	} else {
+		for i := 0; i < p.size; i++ {
+			var row, col []coordinate
+			for j := 0; j < p.size; j++ {
+				row = append(row, coordinate{i, j})
+				col = append(col, coordinate{j, i})
+			}
+			houses = append(houses, row, col)
+		}
+	}
+
+	indexes := make([][]int, 0, len(houses)+len(p.boxes()))
+	for _, house := range append(houses, p.boxes()...) {
+		idx := make([]int, len(house))
+		for i, cell := range house {
+			idx[i] = cell.row*size + cell.col
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
@@ -2,81 +2,95 @@ package board
 
 // Handles all cursor movements
 
-// all cursor[Dir] funcs move currCell, and delete all entries in selectedCells
-func (m *Model) cursorDown() {
-	m.selectedCells = make(map[coordinate]bool)
-	if m.currCell.row < len(m.currBoardState.board)-1 {
-		m.currCell.row++
-	} else {
-		m.currCell.row = 0
+// directions the cursor moves in, indexes into a navGraph entry
+type direction int
+
+const (
+	up direction = iota
+	down
+	left
+	right
+)
+
+/*
+navGraph holds the cell the cursor moves to from each cell in each direction.
+The cursor moves to the next cell in that direction that is on the board,
+skipping holes, and wraps around to the other end of the row or col. On a
+classic board that is just the neighboring cell, on a samurai board moving
+right from the top left grid jumps the gap to the top right grid
+*/
+type navGraph map[coordinate][4]coordinate
+
+// builds the navigation graph of board, holes get no entry since the cursor never lands on them
+func newNavGraph(board [][]boardCell) navGraph {
+	size := len(board)
+	steps := [4]coordinate{up: {-1, 0}, down: {1, 0}, left: {0, -1}, right: {0, 1}}
+
+	nav := make(navGraph)
+	for i := range board {
+		for j := range board[i] {
+			if board[i][j].hole {
+				continue
+			}
+			var next [4]coordinate
+			for d, step := range steps {
+				cell := coordinate{i, j}
+				for {
+					cell.row = (cell.row + step.row + size) % size
+					cell.col = (cell.col + step.col + size) % size
+					if !board[cell.row][cell.col].hole {
+						break
+					}
+				}
+				next[d] = cell
+			}
+			nav[coordinate{i, j}] = next
+		}
 	}
-	m.selectedCells[m.currCell] = true
+	return nav
 }
 
-func (m *Model) cursorUp() {
-	m.selectedCells = make(map[coordinate]bool)
-	if m.currCell.row > 0 {
-		m.currCell.row--
-	} else {
-		m.currCell.row = len(m.currBoardState.board) - 1
+// moves currCell in direction d, and deletes all entries in selectedCells unless highlight is set.
+// The new currCell is always selected
+func (m *Model) moveCursor(d direction, highlight bool) {
+	if !highlight {
+		m.selectedCells = make(map[coordinate]bool)
 	}
+	m.currCell = m.nav[m.currCell][d]
 	m.selectedCells[m.currCell] = true
 }
 
+// all cursor[Dir] funcs move currCell, and delete all entries in selectedCells
+func (m *Model) cursorDown() {
+	m.moveCursor(down, false)
+}
+
+func (m *Model) cursorUp() {
+	m.moveCursor(up, false)
+}
+
 func (m *Model) cursorLeft() {
-	m.selectedCells = make(map[coordinate]bool)
-	if m.currCell.col > 0 {
-		m.currCell.col--
-	} else {
-		m.currCell.col = len(m.currBoardState.board[0]) - 1
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(left, false)
 }
 
 func (m *Model) cursorRight() {
-	m.selectedCells = make(map[coordinate]bool)
-	if m.currCell.col < len(m.currBoardState.board[0])-1 {
-		m.currCell.col++
-	} else {
-		m.currCell.col = 0
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(right, false)
 }
 
 // all cursorHighlight[Dir] funcs move currCell in correct direction
 // as well as add the new currCell into selected Cells
 func (m *Model) cursorHighlightDown() {
-	if m.currCell.row < len(m.currBoardState.board)-1 {
-		m.currCell.row++
-	} else {
-		m.currCell.row = 0
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(down, true)
 }
 
 func (m *Model) cursorHighlightUp() {
-	if m.currCell.row > 0 {
-		m.currCell.row--
-	} else {
-		m.currCell.row = len(m.currBoardState.board) - 1
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(up, true)
 }
 
 func (m *Model) cursorHighlightLeft() {
-	if m.currCell.col > 0 {
-		m.currCell.col--
-	} else {
-		m.currCell.col = len(m.currBoardState.board[0]) - 1
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(left, true)
 }
 
 func (m *Model) cursorHighlightRight() {
-	if m.currCell.col < len(m.currBoardState.board[0])-1 {
-		m.currCell.col++
-	} else {
-		m.currCell.col = 0
-	}
-	m.selectedCells[m.currCell] = true
+	m.moveCursor(right, true)
 }
@@ -0,0 +1,135 @@
+package board
+
+import "math/rand"
+
+// how killer puzzles are built for each difficulty, indexed by mode
+var killerLevels = []struct {
+	minCage, maxCage int // cage sizes
+	givens           int // given cells on a 9x9 board before any needed to make the solution unique
+}{
+	{minCage: 1, maxCage: 3, givens: 12}, // easy
+	{minCage: 2, maxCage: 4, givens: 4},  // medium
+	{minCage: 2, maxCage: 5, givens: 0},  // hard
+	{minCage: 2, maxCage: 6, givens: 0},  // expert
+}
+
+// guesses the solver may take to prove a 9x9 killer puzzle unique before we give it another
+// given cell. Bigger boards get fewer guesses as each one costs more, smaller boards get more
+const killerMaxNodes = 100000
+
+/*
+Generates a killer sudoku of size for difficulty mode (0-3), with any variant rules on top.
+We fill a random grid, then cut it into cages by growing each cage from a
+random cell into neighboring cells that don't repeat a value already in the
+cage. Then we give away cells until the solver can prove there is only one
+solution, laid out with symmetry
+*/
+func generateKiller(rng *rand.Rand, mode, size int, variantNames []string, symmetry string) (Puzzle, error) {
+	level := killerLevels[mode]
+	givens := scaleToCells(level.givens, size*size)
+
+	p := Puzzle{size: size, game: newGrid(size), variants: variantNames, symmetry: symmetry}
+	answerKey, err := fillPuzzle(p, rng)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	p.answerKey = answerKey
+	p.cages = makeCages(answerKey, level.minCage, level.maxCage, rng)
+
+	// cells are given away together with the cells the symmetry maps them to
+	orbits := symmetryOrbits(p.cells(), size, symmetry)
+	give := func(orbit []coordinate) {
+		for _, cell := range orbit {
+			p.game[cell.row][cell.col] = answerKey[cell.row][cell.col]
+		}
+	}
+
+	// give away cells in random order for the difficulty
+	order := rng.Perm(len(orbits))
+	next := 0
+	for given := 0; given < givens; next++ {
+		give(orbits[order[next]])
+		given += len(orbits[order[next]])
+	}
+
+	// then give away cells until the solver can prove there is only one solution.
+	// If it found two solutions, we give away a cell they disagree on, which rules
+	// out at least one of them, otherwise we give away the next random cell
+	for {
+		unique, first, second := uniqueSolution(p.game, size, p.constraints(), killerMaxNodes*81/(size*size))
+		if unique {
+			break
+		}
+
+		orbit := -1
+		if second != nil {
+			for _, idx := range order {
+				for _, cell := range orbits[idx] {
+					if first[cell.row][cell.col] != second[cell.row][cell.col] {
+						orbit = idx
+					}
+				}
+				if orbit != -1 {
+					break
+				}
+			}
+		}
+		for orbit == -1 || p.game[orbits[orbit][0].row][orbits[orbit][0].col] != -1 {
+			orbit = order[next]
+			next++
+		}
+		give(orbits[orbit])
+	}
+
+	return p, nil
+}
+
+// cuts a filled grid into cages of minSize to maxSize cells, cages can end up
+// smaller than minSize when they are boxed in by other cages
+func makeCages(g grid, minSize, maxSize int, rng *rand.Rand) []cage {
+	size := len(g)
+	inCage := make(map[coordinate]bool)
+	var cages []cage
+
+	for _, idx := range rng.Perm(size * size) {
+		start := coordinate{idx / size, idx % size}
+		if inCage[start] {
+			continue
+		}
+
+		target := minSize + rng.Intn(maxSize-minSize+1)
+		c := cage{cells: []coordinate{start}}
+		inCage[start] = true
+		used := map[int8]bool{g[start.row][start.col]: true}
+
+		for len(c.cells) < target {
+			// every free neighbor of the cage that doesn't repeat a value
+			var options []coordinate
+			for _, cell := range c.cells {
+				for _, d := range []coordinate{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+					n := coordinate{cell.row + d.row, cell.col + d.col}
+					if n.row < 0 || n.row >= size || n.col < 0 || n.col >= size {
+						continue
+					}
+					if !inCage[n] && !used[g[n.row][n.col]] {
+						options = append(options, n)
+					}
+				}
+			}
+			if len(options) == 0 {
+				break
+			}
+			next := options[rng.Intn(len(options))]
+			c.cells = append(c.cells, next)
+			inCage[next] = true
+			used[g[next.row][next.col]] = true
+		}
+
+		for _, cell := range c.cells {
+			c.sum += int(g[cell.row][cell.col])
+		}
+		cages = append(cages, c)
+	}
+
+	return cages
+}
@@ -0,0 +1,652 @@
+package board
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// a puzzle to play, either generated or loaded from a puzzle file
+type Puzzle struct {
+	size      int  // values go from 1 to size, the board is size x size unless it is samurai
+	samurai   bool // five 9x9 grids on a 21x21 board, see samurai.go
+	game      grid // starting values, -1 for empty cells
+	answerKey grid
+	regions   [][]coordinate // jigsaw regions replacing the boxes, nil for classic boxes
+	cages     []cage         // killer cages, empty for classic puzzles
+	variants  []string       // names of variant rules on top of the classic rules
+	thermos   [][]coordinate // thermometers, bulb first
+	arrows    [][]coordinate // arrows, circle first
+	dots      []kropkiDot
+	symmetry  string // how the givens of a generated puzzle are laid out, see Symmetries
+}
+
+// returns the regions of the puzzle, the classic boxes unless it is a jigsaw puzzle
+func (p Puzzle) boxes() [][]coordinate {
+	switch {
+	case p.regions != nil:
+		return p.regions
+	case p.samurai:
+		return samuraiBoxes()
+	}
+	return boxRegions(p.size)
+}
+
+// returns the puzzle as a grid of the sudoku package, false if it has rules on top of
+// the classic ones, which the sudoku package doesn't know
+func (p Puzzle) sudokuGrid() (sudoku.Grid, bool) {
+	if p.samurai || p.regions != nil || len(p.cages) > 0 || len(p.variants) > 0 || p.HasClues() {
+		return sudoku.Grid{}, false
+	}
+	g, err := sudoku.Parse(p.Line())
+	return g, err == nil
+}
+
+// returns a grid of the puzzle's shape with every cell empty
+func (p Puzzle) emptyGrid() grid {
+	if p.samurai {
+		return newSamuraiGrid()
+	}
+	return newGrid(p.size)
+}
+
+// returns every cell of the puzzle in reading order, leaving out holes
+func (p Puzzle) cells() []coordinate {
+	var cells []coordinate
+	for i, row := range p.emptyGrid() {
+		for j, val := range row {
+			if val != hole {
+				cells = append(cells, coordinate{i, j})
+			}
+		}
+	}
+	return cells
+}
+
+// returns the rules of the puzzle
+func (p Puzzle) constraints() []Constraint {
+	var constraints []Constraint
+	if p.samurai {
+		constraints = append(constraints, newSamurai())
+	} else {
+		constraints = append(constraints, newClassic(p.boxes()))
+	}
+	for _, name := range p.variants {
+		constraints = append(constraints, variants[name](p.size))
+	}
+	if len(p.cages) > 0 {
+		constraints = append(constraints, newKillerCages(p.cages))
+	}
+	if len(p.thermos) > 0 {
+		constraints = append(constraints, newThermometers(p.size, p.thermos))
+	}
+	if len(p.arrows) > 0 {
+		constraints = append(constraints, newArrows(p.size, p.arrows))
+	}
+	if len(p.dots) > 0 {
+		constraints = append(constraints, newKropkiDots(p.size, p.dots))
+	}
+	return constraints
+}
+
+// reads a puzzle file, see ParsePuzzle for the format
+func LoadPuzzle(path string) (Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	defer f.Close()
+
+	return ParsePuzzle(f)
+}
+
+// Parses a puzzle file. Blank lines and lines starting with # are ignored,
+// everything else is a section:
+//
+//	size <n>               the board is n x n, see Sizes. Has to be the first section,
+//	                       boards are 9x9 without it
+//	grid                   followed by n rows of n values, 1-9 then A-G for given cells
+//	                       and . or 0 for empty cells. Optional for killer puzzles
+//	cage <sum> <cells...>  a killer cage, cells are written as r<row>c<col> counting
+//	                       from 1, i.e. cage 15 r1c1 r1c2 r2c1
+//	regions                followed by n rows of n labels, cells with the same label form
+//	                       a jigsaw region that replaces the boxes. Any character but a
+//	                       space is a label, there must be n regions of n cells each
+//	variant <name>         adds a variant rule, see VariantNames
+//	thermo <cells...>      a thermometer, values strictly increase from the bulb, its
+//	                       first cell. Each cell has to touch the one before it,
+//	                       diagonals count
+//	arrow <cells...>       an arrow, the values along it add up to the value in its
+//	                       circle, its first cell. Cells touch like thermometer cells
+//	kropki <white|black> <cell> <cell>
+//	                       a kropki dot between two side by side cells, values on a
+//	                       white dot are consecutive and one is double the other on a black dot
+//	samurai                followed by 21 rows of 21 values like a grid, the five grids of a
+//	                       samurai puzzle. Cells between the grids must be . and it has to
+//	                       be the only section besides symmetry
+//	symmetry <name>        the givens are laid out with a symmetry, see Symmetries. It only
+//	                       describes the puzzle, but the givens have to fit it
+//
+// The answer key is found by solving the puzzle, so a puzzle with no
+// solution is an error
+func ParsePuzzle(r io.Reader) (Puzzle, error) {
+	p, err := ReadPuzzle(r)
+	if err != nil {
+		return Puzzle{}, err
+	}
+
+	n, answerKey := solve(p.game, p.size, p.constraints(), 1)
+	if n == 0 {
+		return Puzzle{}, fmt.Errorf("puzzle has no solution")
+	}
+	p.answerKey = answerKey
+
+	return p, nil
+}
+
+// reads a puzzle file like ParsePuzzle without solving it, for checking puzzles
+// that may have no solution or more than one. The puzzle has no answer key
+func ReadPuzzle(r io.Reader) (Puzzle, error) {
+	p := Puzzle{size: 9, game: newGrid(9)}
+	caged := make(map[coordinate]bool)
+	first := true
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	nextLine := func() (string, bool) {
+		for scanner.Scan() {
+			lineNum++
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				return line, true
+			}
+		}
+		return "", false
+	}
+
+	for {
+		line, ok := nextLine()
+		if !ok {
+			break
+		}
+
+		fields := strings.Fields(line)
+		if (fields[0] == "size" || fields[0] == "samurai") && !first {
+			return Puzzle{}, fmt.Errorf("line %d: %s has to be the first section", lineNum, fields[0])
+		}
+		if p.samurai && fields[0] != "symmetry" {
+			return Puzzle{}, fmt.Errorf("line %d: %s can't be used in a samurai puzzle", lineNum, fields[0])
+		}
+		first = false
+
+		switch fields[0] {
+		case "size":
+			if len(fields) != 2 {
+				return Puzzle{}, fmt.Errorf("line %d: want size <%s>", lineNum, sizeNames())
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil || !IsSize(size) {
+				return Puzzle{}, fmt.Errorf("line %d: want size <%s>", lineNum, sizeNames())
+			}
+			p.size = size
+			p.game = newGrid(size)
+
+		case "samurai":
+			p.samurai = true
+			p.game = newGrid(samuraiSize)
+			for i := 0; i < samuraiSize; i++ {
+				row, ok := nextLine()
+				if !ok {
+					return Puzzle{}, fmt.Errorf("line %d: samurai has %d rows, want %d", lineNum, i, samuraiSize)
+				}
+				if err := parseGridRow(p.game[i], row, p.size); err != nil {
+					return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				for j := range p.game[i] {
+					if inSamurai(coordinate{i, j}) {
+						continue
+					}
+					if p.game[i][j] != -1 {
+						return Puzzle{}, fmt.Errorf("line %d: r%dc%d is between the grids, want .", lineNum, i+1, j+1)
+					}
+					p.game[i][j] = hole
+				}
+			}
+
+		case "grid":
+			for i := 0; i < p.size; i++ {
+				row, ok := nextLine()
+				if !ok {
+					return Puzzle{}, fmt.Errorf("line %d: grid has %d rows, want %d", lineNum, i, p.size)
+				}
+				if err := parseGridRow(p.game[i], row, p.size); err != nil {
+					return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+			}
+
+		case "cage":
+			if len(fields) < 3 {
+				return Puzzle{}, fmt.Errorf("line %d: cage needs a sum and at least one cell", lineNum)
+			}
+			sum, err := strconv.Atoi(fields[1])
+			if err != nil || sum < 1 {
+				return Puzzle{}, fmt.Errorf("line %d: bad cage sum %q", lineNum, fields[1])
+			}
+			c := cage{sum: sum}
+			for _, f := range fields[2:] {
+				cell, err := parseCell(f, p.size)
+				if err != nil {
+					return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				if caged[cell] {
+					return Puzzle{}, fmt.Errorf("line %d: cell %s is in more than one cage", lineNum, f)
+				}
+				caged[cell] = true
+				c.cells = append(c.cells, cell)
+			}
+			p.cages = append(p.cages, c)
+
+		case "regions":
+			labels := make([]string, p.size)
+			for i := range labels {
+				row, ok := nextLine()
+				if !ok {
+					return Puzzle{}, fmt.Errorf("line %d: regions has %d rows, want %d", lineNum, i, p.size)
+				}
+				labels[i] = strings.ReplaceAll(row, " ", "")
+			}
+			regions, err := parseRegions(labels)
+			if err != nil {
+				return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			p.regions = regions
+
+		case "thermo", "arrow":
+			path, err := parsePath(fields[1:], p.size)
+			if err != nil {
+				return Puzzle{}, fmt.Errorf("line %d: %s %w", lineNum, fields[0], err)
+			}
+			if fields[0] == "thermo" {
+				p.thermos = append(p.thermos, path)
+			} else {
+				p.arrows = append(p.arrows, path)
+			}
+
+		case "kropki":
+			if len(fields) != 4 || (fields[1] != "white" && fields[1] != "black") {
+				return Puzzle{}, fmt.Errorf("line %d: want kropki <white|black> <cell> <cell>", lineNum)
+			}
+			a, err := parseCell(fields[2], p.size)
+			if err != nil {
+				return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			b, err := parseCell(fields[3], p.size)
+			if err != nil {
+				return Puzzle{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			if abs(a.row-b.row)+abs(a.col-b.col) != 1 {
+				return Puzzle{}, fmt.Errorf("line %d: kropki cells %s and %s are not side by side", lineNum, fields[2], fields[3])
+			}
+			p.dots = append(p.dots, kropkiDot{a: a, b: b, black: fields[1] == "black"})
+
+		case "symmetry":
+			if len(fields) != 2 || !IsSymmetry(fields[1]) {
+				return Puzzle{}, fmt.Errorf("line %d: want symmetry <%s>", lineNum, strings.Join(Symmetries, "|"))
+			}
+			p.symmetry = fields[1]
+
+		case "variant":
+			if len(fields) != 2 || !IsVariant(fields[1]) {
+				return Puzzle{}, fmt.Errorf("line %d: want variant <%s>", lineNum, strings.Join(VariantNames(), "|"))
+			}
+			p.variants = append(p.variants, fields[1])
+
+		default:
+			return Puzzle{}, fmt.Errorf("line %d: unknown section %q", lineNum, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Puzzle{}, err
+	}
+	if !p.symmetric() {
+		return Puzzle{}, fmt.Errorf("the givens aren't laid out with %s symmetry", p.symmetry)
+	}
+
+	return p, nil
+}
+
+/*
+Parses a classic puzzle written on one line, the common format of puzzle
+collections: the rows one after the other, 1-9 then A-G for given cells and
+. or 0 for empty cells. The length of the line picks the size, 81 values for
+9x9, and 441 are a samurai puzzle with . for the cells between the grids.
+Spaces are ignored. Like ReadPuzzle the puzzle isn't solved
+*/
+func ParsePuzzleLine(line string) (Puzzle, error) {
+	line = strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+
+	var p Puzzle
+	switch {
+	case len(line) == samuraiSize*samuraiSize:
+		p = Puzzle{size: 9, samurai: true, game: newGrid(samuraiSize)}
+	default:
+		for _, size := range Sizes() {
+			if len(line) == size*size {
+				p = Puzzle{size: size, game: newGrid(size)}
+			}
+		}
+	}
+	if p.game == nil {
+		sizes := Sizes()
+		lengths := make([]string, len(sizes))
+		for i, size := range sizes {
+			lengths[i] = strconv.Itoa(size * size)
+		}
+		return Puzzle{}, fmt.Errorf("puzzle has %d values, want %s, or 441 for samurai", len(line), strings.Join(lengths, ", "))
+	}
+
+	empty := p.emptyGrid()
+	for i := range p.game {
+		row := line[i*len(p.game) : (i+1)*len(p.game)]
+		if err := parseGridRow(p.game[i], row, p.size); err != nil {
+			return Puzzle{}, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		for j := range p.game[i] {
+			if empty[i][j] != hole {
+				continue
+			}
+			if p.game[i][j] != -1 {
+				return Puzzle{}, fmt.Errorf("r%dc%d is between the grids, want .", i+1, j+1)
+			}
+			p.game[i][j] = hole
+		}
+	}
+	return p, nil
+}
+
+// returns the given cells of the puzzle in the format of ParsePuzzleLine. Killer cages,
+// jigsaw regions, variants and clues have no place on the line and are left out
+func (p Puzzle) Line() string {
+	return gridLine(p.game)
+}
+
+// returns the answer key written like Line, empty for puzzles read without solving
+func (p Puzzle) Solution() string {
+	if p.answerKey == nil {
+		return ""
+	}
+	return gridLine(p.answerKey)
+}
+
+// returns how the givens are laid out, see Symmetries, empty if we don't know
+func (p Puzzle) Symmetry() string {
+	return p.symmetry
+}
+
+// returns the highest value of the puzzle, values go from 1 to its size. It is the
+// board size for all but samurai puzzles, which are 21x21 with values up to 9
+func (p Puzzle) Size() int {
+	return p.size
+}
+
+// returns the region index of every cell, its box or jigsaw region, -1 for holes
+func (p Puzzle) Regions() [][]int {
+	return regionIndex(p.boxes(), len(p.game))
+}
+
+// returns the killer cage index of every cell, -1 for cells in no cage, and the sum of every cage
+func (p Puzzle) Cages() ([][]int, []int) {
+	cageOf := regionIndex(nil, len(p.game))
+	sums := make([]int, len(p.cages))
+	for idx, c := range p.cages {
+		for _, cell := range c.cells {
+			cageOf[cell.row][cell.col] = idx
+		}
+		sums[idx] = c.sum
+	}
+	return cageOf, sums
+}
+
+// returns the names of the variant rules on top of the classic rules
+func (p Puzzle) Variants() []string {
+	return p.variants
+}
+
+// returns true if the puzzle has thermometers, arrows or kropki dots
+func (p Puzzle) HasClues() bool {
+	return len(p.thermos) > 0 || len(p.arrows) > 0 || len(p.dots) > 0
+}
+
+// returns the candidates the givens leave every empty cell, like the board's pencil
+// marks. Given cells and holes have none
+func (p Puzzle) Candidates() [][]sudoku.Candidates {
+	s := solver{values: p.size, constraints: p.constraints()}
+	masks := s.initialMasks(p.game)
+	candidates := make([][]sudoku.Candidates, len(p.game))
+	for i := range candidates {
+		candidates[i] = make([]sudoku.Candidates, len(p.game))
+		for j := range candidates[i] {
+			if masks != nil && p.game[i][j] == -1 {
+				candidates[i][j] = masks[i*len(p.game)+j]
+			}
+		}
+	}
+	return candidates
+}
+
+// returns true if the givens are laid out with the puzzle's symmetry
+func (p Puzzle) symmetric() bool {
+	for _, orbit := range symmetryOrbits(p.cells(), len(p.game), p.symmetry) {
+		for _, cell := range orbit {
+			if (p.game[cell.row][cell.col] == -1) != (p.game[orbit[0].row][orbit[0].col] == -1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// returns the number of given cells
+func (p Puzzle) Givens() int {
+	givens := 0
+	for _, row := range p.game {
+		for _, val := range row {
+			if val != -1 && val != hole {
+				givens++
+			}
+		}
+	}
+	return givens
+}
+
+// returns the puzzle written as a puzzle file, see ParsePuzzle
+func (p Puzzle) Text() string {
+	var b strings.Builder
+	if p.samurai {
+		b.WriteString("samurai\n")
+	} else if p.size != 9 {
+		fmt.Fprintf(&b, "size %d\n", p.size)
+	}
+	if !p.samurai {
+		b.WriteString("grid\n")
+	}
+	line := gridLine(p.game)
+	for i := range p.game {
+		b.WriteString(line[i*len(p.game):(i+1)*len(p.game)] + "\n")
+	}
+
+	if p.regions != nil {
+		labels := newGrid(p.size)
+		for idx, region := range p.regions {
+			for _, cell := range region {
+				labels[cell.row][cell.col] = int8(idx + 1)
+			}
+		}
+		b.WriteString("regions\n")
+		line := gridLine(labels)
+		for i := range labels {
+			b.WriteString(line[i*p.size:(i+1)*p.size] + "\n")
+		}
+	}
+	for _, c := range p.cages {
+		fmt.Fprintf(&b, "cage %d %s\n", c.sum, cellList(c.cells))
+	}
+	for _, name := range p.variants {
+		fmt.Fprintf(&b, "variant %s\n", name)
+	}
+	if p.symmetry != "" {
+		fmt.Fprintf(&b, "symmetry %s\n", p.symmetry)
+	}
+	for _, path := range p.thermos {
+		fmt.Fprintf(&b, "thermo %s\n", cellList(path))
+	}
+	for _, path := range p.arrows {
+		fmt.Fprintf(&b, "arrow %s\n", cellList(path))
+	}
+	for _, dot := range p.dots {
+		color := "white"
+		if dot.black {
+			color = "black"
+		}
+		fmt.Fprintf(&b, "kropki %s %s\n", color, cellList([]coordinate{dot.a, dot.b}))
+	}
+	return b.String()
+}
+
+// writes cells the way puzzle files do, r<row>c<col> counting from 1
+func cellList(cells []coordinate) string {
+	names := make([]string, len(cells))
+	for i, cell := range cells {
+		names[i] = Cell{Row: cell.row, Col: cell.col}.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// writes the values of g on one line, . for empty cells and holes
+func gridLine(g grid) string {
+	var b strings.Builder
+	for _, row := range g {
+		for _, val := range row {
+			if val == -1 || val == hole {
+				b.WriteByte('.')
+			} else {
+				b.WriteString(DigitString(val))
+			}
+		}
+	}
+	return b.String()
+}
+
+// parses one row of a grid section into row, values go from 1 to values
+func parseGridRow(row []int8, line string, values int) error {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) != len(row) {
+		return fmt.Errorf("grid row %q has %d values, want %d", line, len(line), len(row))
+	}
+	for j, ch := range line {
+		val := digitValue(string(ch))
+		switch {
+		case ch == '.' || ch == '0':
+			row[j] = -1
+		case val >= 1 && int(val) <= values:
+			row[j] = val
+		default:
+			return fmt.Errorf("bad value %q in grid row %q", ch, line)
+		}
+	}
+	return nil
+}
+
+// turns the rows of a regions section into regions, in order of their labels' first appearance
+func parseRegions(rows []string) ([][]coordinate, error) {
+	var regions [][]coordinate
+	var labels []rune
+	index := make(map[rune]int)
+	for i, row := range rows {
+		if len([]rune(row)) != len(rows) {
+			return nil, fmt.Errorf("regions row %q has %d labels, want %d", row, len([]rune(row)), len(rows))
+		}
+		for j, label := range []rune(row) {
+			idx, ok := index[label]
+			if !ok {
+				idx = len(regions)
+				index[label] = idx
+				labels = append(labels, label)
+				regions = append(regions, nil)
+			}
+			regions[idx] = append(regions[idx], coordinate{i, j})
+		}
+	}
+
+	if len(regions) != len(rows) {
+		return nil, fmt.Errorf("found %d regions, want %d", len(regions), len(rows))
+	}
+	for idx, region := range regions {
+		if len(region) != len(rows) {
+			return nil, fmt.Errorf("region %q has %d cells, want %d", labels[idx], len(region), len(rows))
+		}
+	}
+	return regions, nil
+}
+
+// parses the cells of a thermometer or arrow, there must be at least two and
+// each has to touch the one before it
+func parsePath(fields []string, size int) ([]coordinate, error) {
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("needs at least two cells")
+	}
+	var path []coordinate
+	seen := make(map[coordinate]bool)
+	for i, f := range fields {
+		cell, err := parseCell(f, size)
+		if err != nil {
+			return nil, err
+		}
+		if seen[cell] {
+			return nil, fmt.Errorf("has cell %s twice", f)
+		}
+		if i > 0 {
+			prev := path[i-1]
+			if abs(cell.row-prev.row) > 1 || abs(cell.col-prev.col) > 1 {
+				return nil, fmt.Errorf("cell %s does not touch %s", f, fields[i-1])
+			}
+		}
+		seen[cell] = true
+		path = append(path, cell)
+	}
+	return path, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// parses a cell written as r<row>c<col>, counting from 1, on a board of size
+func parseCell(s string, size int) (coordinate, error) {
+	cell, err := sudoku.ParseCell(s)
+	if err != nil {
+		return coordinate{}, err
+	}
+	if cell.Row >= size || cell.Col >= size {
+		return coordinate{}, fmt.Errorf("cell %q is off the board", s)
+	}
+	return coordinate{cell.Row, cell.Col}, nil
+}
+
+// returns the sizes we can play as a|b|c, for error messages
+func sizeNames() string {
+	sizes := Sizes()
+	names := make([]string, len(sizes))
+	for i, size := range sizes {
+		names[i] = strconv.Itoa(size)
+	}
+	return strings.Join(names, "|")
+}
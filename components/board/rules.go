@@ -0,0 +1,411 @@
+package board
+
+import (
+	"sort"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// grid is the bare values of a board, -1 for empty cells.
+// It is what constraints and the solver work on
+type grid [][]int8
+
+// value of the cells of a grid that aren't on the board, like the gaps between the grids
+// of a samurai puzzle. Holes are in no house and count as filled
+const hole int8 = 0
+
+func newGrid(size int) grid {
+	g := make(grid, size)
+	for i := range g {
+		g[i] = make([]int8, size)
+		for j := range g[i] {
+			g[i][j] = -1
+		}
+	}
+	return g
+}
+
+func (g grid) copyGrid() grid {
+	c := make(grid, len(g))
+	for i := range g {
+		c[i] = append([]int8(nil), g[i]...)
+	}
+	return c
+}
+
+// returns the values of the board state as a grid
+func (b BoardState) grid() grid {
+	g := newGrid(len(b.board))
+	for i := range b.board {
+		for j := range b.board[i] {
+			g[i][j] = b.board[i][j].game
+		}
+	}
+	return g
+}
+
+/*
+A Constraint is one rule of the puzzle, i.e. classic sudoku is the row, col and box
+rule, killer sudoku adds cage rules on top of it and variants add their own rules.
+Everything that depends on the rules goes through the puzzle's constraints: the
+board is won when it is full and every constraint is valid at every cell, the
+solver uses the same check to prune its search, setting a cell removes the pencil
+marks its constraints eliminate, and peer highlighting shows the cells' peers
+*/
+type Constraint interface {
+	// returns the cells that can never hold the same value as cell
+	Peers(cell coordinate) []coordinate
+
+	// returns false if the value at cell breaks the rule given the other filled cells in g.
+	// empty cells never break a rule
+	Valid(g grid, cell coordinate) bool
+
+	// returns the pencil marks ruled out by placing val at cell
+	Eliminations(cell coordinate, val int8) []candidate
+}
+
+// a possible value for a cell, i.e. a pencil mark
+type candidate struct {
+	cell coordinate
+	val  int8
+}
+
+// returns true if the value at cell is valid for every constraint
+func validAt(constraints []Constraint, g grid, cell coordinate) bool {
+	for _, c := range constraints {
+		if !c.Valid(g, cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns true if g is full and follows every constraint
+func satisfied(constraints []Constraint, g grid) bool {
+	for i := range g {
+		for j := range g[i] {
+			if g[i][j] == -1 || !validAt(constraints, g, coordinate{i, j}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// returns true if any constraint makes a and b peers
+func arePeers(constraints []Constraint, a, b coordinate) bool {
+	for _, c := range constraints {
+		for _, p := range c.Peers(a) {
+			if p == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+A peerConstraint is a rule where no cell may share its value with any of its peers.
+Classic rows, cols and boxes, the diagonals of sudoku-x, and the anti-knight and
+anti-king rules are all peer constraints, they only differ in who the peers are
+*/
+type peerConstraint struct {
+	peers map[coordinate][]coordinate
+}
+
+func (p peerConstraint) Peers(cell coordinate) []coordinate {
+	return p.peers[cell]
+}
+
+func (p peerConstraint) Valid(g grid, cell coordinate) bool {
+	val := g[cell.row][cell.col]
+	if val == -1 {
+		return true
+	}
+	for _, peer := range p.peers[cell] {
+		if g[peer.row][peer.col] == val {
+			return false
+		}
+	}
+	return true
+}
+
+func (p peerConstraint) Eliminations(cell coordinate, val int8) []candidate {
+	eliminated := make([]candidate, 0, len(p.peers[cell]))
+	for _, peer := range p.peers[cell] {
+		eliminated = append(eliminated, candidate{peer, val})
+	}
+	return eliminated
+}
+
+// makes a peer constraint where the cells of each house may not repeat a value,
+// every cell sharing a house with a cell is its peer
+func newHouseConstraint(houses [][]coordinate) peerConstraint {
+	seen := make(map[coordinate]map[coordinate]bool)
+	peers := make(map[coordinate][]coordinate)
+	for _, house := range houses {
+		for _, cell := range house {
+			if seen[cell] == nil {
+				seen[cell] = make(map[coordinate]bool)
+			}
+			for _, other := range house {
+				if other != cell && !seen[cell][other] {
+					seen[cell][other] = true
+					peers[cell] = append(peers[cell], other)
+				}
+			}
+		}
+	}
+	return peerConstraint{peers: peers}
+}
+
+// makes a peer constraint where a cell may not share its value with any cell
+// one of moves away from it, i.e. a chess knight's or king's moves
+func newMoveConstraint(size int, moves []coordinate) peerConstraint {
+	peers := make(map[coordinate][]coordinate)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			for _, m := range moves {
+				other := coordinate{i + m.row, j + m.col}
+				if other.row >= 0 && other.row < size && other.col >= 0 && other.col < size {
+					peers[coordinate{i, j}] = append(peers[coordinate{i, j}], other)
+				}
+			}
+		}
+	}
+	return peerConstraint{peers: peers}
+}
+
+// returns the board sizes we can play, the grid sizes of the sudoku package
+func Sizes() []int {
+	return sudoku.Sizes()
+}
+
+// returns true if we can play boards of size
+func IsSize(size int) bool {
+	return sudoku.IsSize(size)
+}
+
+// returns the rows and cols of a box on a board of size, see sudoku.BoxShape
+func boxShape(size int) (int, int) {
+	return sudoku.BoxShape(size)
+}
+
+// returns the classic boxes of a board of size as regions
+func boxRegions(size int) [][]coordinate {
+	boxRows, boxCols := boxShape(size)
+
+	// convert the box index (0,0 0,1 0,2 for top three boxes of a 9x9 board etc)
+	// to the coords of the top left cell in the box
+	boxesPerRow := size / boxCols
+	boxes := make([][]coordinate, 0, size)
+	for b := 0; b < size; b++ {
+		realRow, realCol := b/boxesPerRow*boxRows, b%boxesPerRow*boxCols
+		var box []coordinate
+		for i := realRow; i < realRow+boxRows; i++ {
+			for j := realCol; j < realCol+boxCols; j++ {
+				box = append(box, coordinate{i, j})
+			}
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes
+}
+
+// classic sudoku rule, no repeated values in any row, col, or region. Regions are
+// the boxes for classic sudoku and irregular shapes for jigsaw sudoku
+func newClassic(regions [][]coordinate) peerConstraint {
+	size := len(regions)
+	var houses [][]coordinate
+	for i := 0; i < size; i++ {
+		var row, col []coordinate
+		for j := 0; j < size; j++ {
+			row = append(row, coordinate{i, j})
+			col = append(col, coordinate{j, i})
+		}
+		houses = append(houses, row, col)
+	}
+	houses = append(houses, regions...)
+
+	return newHouseConstraint(houses)
+}
+
+// sudoku-x rule, no repeated values on either long diagonal
+func newDiagonal(size int) Constraint {
+	var down, up []coordinate
+	for i := 0; i < size; i++ {
+		down = append(down, coordinate{i, i})
+		up = append(up, coordinate{size - 1 - i, i})
+	}
+	return newHouseConstraint([][]coordinate{down, up})
+}
+
+// anti-knight rule, cells a chess knight's move apart can't share a value
+func newAntiKnight(size int) Constraint {
+	return newMoveConstraint(size, []coordinate{
+		{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1},
+	})
+}
+
+// anti-king rule, cells a chess king's move apart can't share a value. Only the
+// diagonal moves matter, the rest are already in the same row or col
+func newAntiKing(size int) Constraint {
+	return newMoveConstraint(size, []coordinate{
+		{-1, -1}, {-1, 1}, {1, -1}, {1, 1},
+	})
+}
+
+// non-consecutive rule, orthogonally neighboring cells can't hold values that differ by 1
+type nonConsecutive struct {
+	size int
+}
+
+func newNonConsecutive(size int) Constraint {
+	return nonConsecutive{size: size}
+}
+
+func (n nonConsecutive) neighbors(cell coordinate) []coordinate {
+	var neighbors []coordinate
+	for _, d := range []coordinate{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		other := coordinate{cell.row + d.row, cell.col + d.col}
+		if other.row >= 0 && other.row < n.size && other.col >= 0 && other.col < n.size {
+			neighbors = append(neighbors, other)
+		}
+	}
+	return neighbors
+}
+
+// neighbors may hold the same value as far as this rule is concerned, rows and cols forbid that anyway
+func (nonConsecutive) Peers(cell coordinate) []coordinate {
+	return nil
+}
+
+func (n nonConsecutive) Valid(g grid, cell coordinate) bool {
+	val := g[cell.row][cell.col]
+	if val == -1 {
+		return true
+	}
+	for _, other := range n.neighbors(cell) {
+		otherVal := g[other.row][other.col]
+		if otherVal != -1 && (otherVal == val-1 || otherVal == val+1) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n nonConsecutive) Eliminations(cell coordinate, val int8) []candidate {
+	var eliminated []candidate
+	for _, other := range n.neighbors(cell) {
+		if val > 1 {
+			eliminated = append(eliminated, candidate{other, val - 1})
+		}
+		if int(val) < n.size {
+			eliminated = append(eliminated, candidate{other, val + 1})
+		}
+	}
+	return eliminated
+}
+
+// variant rules that can be added to a puzzle, by the name used for --variant and in puzzle files
+var variants = map[string]func(size int) Constraint{
+	"diagonal":       newDiagonal,
+	"antiknight":     newAntiKnight,
+	"antiking":       newAntiKing,
+	"nonconsecutive": newNonConsecutive,
+}
+
+// returns the names of all variant rules, sorted
+func VariantNames() []string {
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// returns true if name is a variant rule
+func IsVariant(name string) bool {
+	_, ok := variants[name]
+	return ok
+}
+
+// a killer sudoku cage, its cells must add up to sum without repeating a value
+type cage struct {
+	sum   int
+	cells []coordinate
+}
+
+// killer sudoku rule for all cages of a puzzle
+type killerCages struct {
+	cages  []cage
+	cageOf map[coordinate]int // index into cages for every caged cell
+}
+
+func newKillerCages(cages []cage) killerCages {
+	cageOf := make(map[coordinate]int)
+	for i, c := range cages {
+		for _, cell := range c.cells {
+			cageOf[cell] = i
+		}
+	}
+	return killerCages{cages: cages, cageOf: cageOf}
+}
+
+// the other cells in cell's cage
+func (k killerCages) Peers(cell coordinate) []coordinate {
+	idx, ok := k.cageOf[cell]
+	if !ok {
+		return nil
+	}
+	peers := make([]coordinate, 0, len(k.cages[idx].cells)-1)
+	for _, other := range k.cages[idx].cells {
+		if other != cell {
+			peers = append(peers, other)
+		}
+	}
+	return peers
+}
+
+/*
+a value is valid if it is not repeated in its cage and the cage can still
+add up to its sum. With k empty cells left in the cage, the smallest they
+can add is 1+2+...+k and the largest is n+(n-1)+...+(n-k+1), where n is
+the largest value on the board
+*/
+func (k killerCages) Valid(g grid, cell coordinate) bool {
+	val := g[cell.row][cell.col]
+	idx, ok := k.cageOf[cell]
+	if val == -1 || !ok {
+		return true
+	}
+
+	total, empty := 0, 0
+	for _, other := range k.cages[idx].cells {
+		otherVal := g[other.row][other.col]
+		if otherVal == -1 {
+			empty++
+			continue
+		}
+		if other != cell && otherVal == val {
+			return false
+		}
+		total += int(otherVal)
+	}
+
+	maxVal := len(g)
+	minLeft := empty * (empty + 1) / 2
+	maxLeft := empty * (2*maxVal - empty + 1) / 2
+	sum := k.cages[idx].sum
+	return total+minLeft <= sum && total+maxLeft >= sum
+}
+
+// values can't repeat in a cage, sums are left for the player to work out
+func (k killerCages) Eliminations(cell coordinate, val int8) []candidate {
+	var eliminated []candidate
+	for _, peer := range k.Peers(cell) {
+		eliminated = append(eliminated, candidate{peer, val})
+	}
+	return eliminated
+}
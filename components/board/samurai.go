@@ -0,0 +1,90 @@
+package board
+
+import "math/rand"
+
+/*
+A samurai puzzle is five classic 9x9 grids on a 21x21 board, one in each corner
+and one in the middle. The middle grid shares its corner boxes with the corner
+grids, so those boxes follow the rules of both grids. The rest of the board
+between the grids is holes
+*/
+const samuraiSize = 21
+
+// top left cells of the five grids
+var samuraiGrids = []coordinate{{0, 0}, {0, 12}, {6, 6}, {12, 0}, {12, 12}}
+
+// returns true if cell is in any of the five grids
+func inSamurai(cell coordinate) bool {
+	for _, g := range samuraiGrids {
+		if cell.row >= g.row && cell.row < g.row+9 && cell.col >= g.col && cell.col < g.col+9 {
+			return true
+		}
+	}
+	return false
+}
+
+// returns an empty samurai board, cells outside the grids are holes
+func newSamuraiGrid() grid {
+	g := newGrid(samuraiSize)
+	for i := range g {
+		for j := range g[i] {
+			if !inSamurai(coordinate{i, j}) {
+				g[i][j] = hole
+			}
+		}
+	}
+	return g
+}
+
+// returns the boxes of all five grids, each shared corner box only once
+func samuraiBoxes() [][]coordinate {
+	var boxes [][]coordinate
+	for boxRow := 0; boxRow < samuraiSize; boxRow += 3 {
+		for boxCol := 0; boxCol < samuraiSize; boxCol += 3 {
+			if !inSamurai(coordinate{boxRow, boxCol}) {
+				continue
+			}
+			var box []coordinate
+			for i := boxRow; i < boxRow+3; i++ {
+				for j := boxCol; j < boxCol+3; j++ {
+					box = append(box, coordinate{i, j})
+				}
+			}
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes
+}
+
+// classic rules for each of the five grids, no repeated values in any row, col, or box of a grid.
+// Cells in the shared boxes are peers of the cells of both their grids
+func newSamurai() peerConstraint {
+	var houses [][]coordinate
+	for _, g := range samuraiGrids {
+		for i := 0; i < 9; i++ {
+			var row, col []coordinate
+			for j := 0; j < 9; j++ {
+				row = append(row, coordinate{g.row + i, g.col + j})
+				col = append(col, coordinate{g.row + j, g.col + i})
+			}
+			houses = append(houses, row, col)
+		}
+	}
+	houses = append(houses, samuraiBoxes()...)
+
+	return newHouseConstraint(houses)
+}
+
+// Generates a samurai puzzle for difficulty mode (0-3) the same way as variant puzzles
+func generateSamurai(rng *rand.Rand, mode int, symmetry string) (Puzzle, error) {
+	p := Puzzle{size: 9, samurai: true, symmetry: symmetry}
+	answerKey, err := fillPuzzle(p, rng)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	p.answerKey = answerKey
+	p.game = p.answerKey.copyGrid()
+	removeGivens(&p, scaleToCells(variantGivens[mode], len(p.cells())), rng)
+
+	return p, nil
+}
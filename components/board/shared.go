@@ -0,0 +1,314 @@
+package board
+
+import (
+	"sort"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+/*
+A shared board is edited by several players at once, see the coop package. Every
+player's board and the server's board start from the same puzzle and apply the
+same edits in the order the server sends them, so they stay the same. A shared
+board doesn't change itself when its player sets, pencils, deletes, undoes or
+redoes, it sends an EditMsg instead and waits for the server to send the edit
+back. Cursor moves stay local, other players' cursors are set with SetPlayers
+*/
+
+// a cell of the board, counting from 0 like coordinate, for use outside the board package
+type Cell = sudoku.Cell
+
+// kinds of edits
+const (
+	EditSet     = "set"
+	EditPencil  = "pencil"
+	EditDelete  = "delete"
+	EditUndo    = "undo"
+	EditRedo    = "redo"
+	EditRestore = "restore"
+)
+
+/*
+an edit to a shared board. set, pencil and delete do the same to Cells as the
+keys do to selected cells, Val is the value to set or pencil mark. Undo and redo
+are only sent to the server, which has each player's history and turns them
+into a restore. A restore puts back the contents of cells exactly, so it can
+also bring a board that joined late up to date
+*/
+type Edit struct {
+	Op       string         `json:"op"`
+	Cells    []Cell         `json:"cells,omitempty"`
+	Val      int8           `json:"val,omitempty"`
+	Contents []CellContents `json:"contents,omitempty"`
+}
+
+// the value and pencil marks of a cell, -1 for no value
+type CellContents struct {
+	Cell
+	Val     int8   `json:"val"`
+	Pencils []int8 `json:"pencils,omitempty"` // sorted
+}
+
+// returns true if c and o hold the same value and pencil marks
+func (c CellContents) Equal(o CellContents) bool {
+	if c.Cell != o.Cell || c.Val != o.Val || len(c.Pencils) != len(o.Pencils) {
+		return false
+	}
+	for i := range c.Pencils {
+		if c.Pencils[i] != o.Pencils[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// this is a tea.Msg type a shared board sends instead of editing itself, the app model
+// passes it on to the server
+type EditMsg struct {
+	Edit Edit
+}
+
+// this is a tea.Msg type a recording board sends after each edit it made, so the edit
+// can be written to a replay and applied again with ApplyEdit
+type Edited struct {
+	Edit Edit
+}
+
+// another player's cursor and selection on a shared board
+type PlayerCursor struct {
+	Player   int
+	Cursor   Cell
+	Selected []Cell
+}
+
+// how another player's cursor or selection is drawn on a cell
+type playerCell struct {
+	color  lipgloss.Color
+	cursor int // number of the player whose cursor is on the cell, 0 if it is only selected
+}
+
+// makes the board shared, or not shared if it lost its server
+func (m *Model) SetShared(shared bool) {
+	m.shared = shared
+}
+
+// makes the board send an Edited message for every edit its player makes
+func (m *Model) SetRecording(recording bool) {
+	m.recording = recording
+}
+
+// returns an Edited message for the edit just made to the selected cells, if recording
+func (m Model) recordEdit(op string, val int8) tea.Cmd {
+	if !m.recording {
+		return nil
+	}
+	edit := Edit{Op: op, Val: val, Cells: sortedCells(m.selectedCells)}
+	return func() tea.Msg {
+		return Edited{Edit: edit}
+	}
+}
+
+// returns an EditMsg for editing the selected cells, used by shared boards
+// instead of changing the board
+func (m Model) sendEdit(op string, val int8) tea.Cmd {
+	edit := Edit{Op: op, Val: val, Cells: sortedCells(m.selectedCells)}
+	return func() tea.Msg {
+		return EditMsg{Edit: edit}
+	}
+}
+
+// applies an edit sent by the server or read from a replay. The cursor and selection
+// are left alone, cells off the board are ignored. Undo and redo go back and forth
+// in the board's own history, the server never sends them
+func (m *Model) ApplyEdit(e Edit) {
+	selected := m.selectedCells
+	defer func() { m.selectedCells = selected }()
+
+	m.selectedCells = make(map[coordinate]bool)
+	for _, c := range e.Cells {
+		if m.onBoard(c) {
+			m.selectedCells[coordinate{c.Row, c.Col}] = true
+		}
+	}
+
+	switch e.Op {
+	case EditSet:
+		m.setCell(e.Val)
+	case EditPencil:
+		m.setPencilCell(e.Val)
+	case EditDelete:
+		m.deleteCell()
+	case EditRestore:
+		m.restoreCells(e.Contents)
+	case EditUndo:
+		m.UndoBoardAction()
+	case EditRedo:
+		m.RedoBoardAction()
+	}
+}
+
+// puts back the contents of cells, given cells and values that don't fit the board are ignored
+func (m *Model) restoreCells(contents []CellContents) {
+	m.makeNewBoardState()
+	for _, c := range contents {
+		if !m.onBoard(c.Cell) || (c.Val != -1 && (c.Val < 1 || int(c.Val) > m.values)) {
+			continue
+		}
+		cell := coordinate{c.Row, c.Col}
+		bc := &m.currBoardState.board[cell.row][cell.col]
+		if bc.given {
+			continue
+		}
+
+		// cellsLeft counts empty and wrong cells, a cell that keeps its value stays wrong
+		_, cellWrong := m.currBoardState.wrongCells[cell]
+		wasLeft := bc.game == -1 || cellWrong
+		if c.Val != bc.game {
+			delete(m.currBoardState.wrongCells, cell)
+			cellWrong = false
+		}
+		switch isLeft := c.Val == -1 || cellWrong; {
+		case isLeft && !wasLeft:
+			m.currBoardState.cellsLeft++
+		case !isLeft && wasLeft:
+			m.currBoardState.cellsLeft--
+		}
+
+		bc.game = c.Val
+		bc.pencils = 0
+		for _, p := range c.Pencils {
+			if p >= 1 && int(p) <= m.values {
+				bc.pencils = bc.pencils.Add(int(p))
+			}
+		}
+	}
+}
+
+// returns the contents of every cell the players fill, in reading order
+func (m Model) Contents() []CellContents {
+	var contents []CellContents
+	for i := range m.currBoardState.board {
+		for j, bc := range m.currBoardState.board[i] {
+			if bc.given {
+				continue
+			}
+			c := CellContents{Cell: Cell{Row: i, Col: j}, Val: bc.game}
+			bc.pencils.Iterate(func(p int) {
+				c.Pencils = append(c.Pencils, int8(p))
+			})
+			contents = append(contents, c)
+		}
+	}
+	return contents
+}
+
+// puts back the cells of a saved game, as returned by Contents. Unlike a restore
+// it can't be undone, the game picks up where it was left
+func (m *Model) Resume(contents []CellContents) {
+	m.restoreCells(contents)
+	m.boardStates = m.boardStates[m.currBoardStateIdx:]
+	m.currBoardStateIdx = 0
+	m.currBoardState = &m.boardStates[0]
+}
+
+// returns true if contents, the values of every cell as returned by Contents, fill the
+// board and follow every rule of its puzzle, the way checkWon judges a win. Givens
+// keep their values whatever contents say, and the board itself is left alone
+func (m Model) Solves(contents []CellContents) bool {
+	b := m.Copy()
+	b.restoreCells(contents)
+	return b.checkForWinManual()
+}
+
+// returns a copy of the board that shares nothing with m, to keep a board and
+// start from it again later, like a replay going back to the start of a game
+func (m Model) Copy() Model {
+	c := m
+	c.boardStates = make([]BoardState, len(m.boardStates))
+	for i := range m.boardStates {
+		c.boardStates[i] = m.boardStates[i].copyBoard()
+	}
+	c.boardStates[m.currBoardStateIdx] = m.currBoardState.copyBoard()
+	c.currBoardState = &c.boardStates[m.currBoardStateIdx]
+
+	c.selectedCells = make(map[coordinate]bool, len(m.selectedCells))
+	for cell := range m.selectedCells {
+		c.selectedCells[cell] = true
+	}
+	c.playerCells = make(map[coordinate]playerCell, len(m.playerCells))
+	for cell, p := range m.playerCells {
+		c.playerCells[cell] = p
+	}
+	return c
+}
+
+// returns the cursor cell and the selected cells in reading order
+func (m Model) Cursor() (Cell, []Cell) {
+	return Cell{Row: m.currCell.row, Col: m.currCell.col}, sortedCells(m.selectedCells)
+}
+
+// moves the cursor and selection, as returned by Cursor, used to mirror another player's
+// board. Cells off the board are ignored
+func (m *Model) SetCursor(cursor Cell, selected []Cell) {
+	if m.onBoard(cursor) {
+		m.currCell = coordinate{cursor.Row, cursor.Col}
+	}
+	m.selectedCells = make(map[coordinate]bool)
+	for _, c := range selected {
+		if m.onBoard(c) {
+			m.selectedCells[coordinate{c.Row, c.Col}] = true
+		}
+	}
+}
+
+// replaces the other players' cursors drawn on a shared board. Where cursors and
+// selections overlap, cursors are drawn over selections and lower players over higher ones.
+// Players are numbered from 1, cursors of other numbers are left out
+func (m *Model) SetPlayers(players []PlayerCursor) {
+	var numbered []PlayerCursor
+	for _, p := range players {
+		if p.Player >= 1 {
+			numbered = append(numbered, p)
+		}
+	}
+	players = numbered
+	sort.Slice(players, func(i, j int) bool { return players[i].Player < players[j].Player })
+
+	m.playerCells = make(map[coordinate]playerCell)
+	for _, p := range players {
+		colors := playerColors[p.Player%len(playerColors)]
+		for _, c := range p.Selected {
+			if _, ok := m.playerCells[coordinate{c.Row, c.Col}]; !ok {
+				m.playerCells[coordinate{c.Row, c.Col}] = playerCell{color: colors.selected}
+			}
+		}
+	}
+	for i := len(players) - 1; i >= 0; i-- {
+		colors := playerColors[players[i].Player%len(playerColors)]
+		cursor := players[i].Cursor
+		m.playerCells[coordinate{cursor.Row, cursor.Col}] = playerCell{color: colors.cursor, cursor: players[i].Player}
+	}
+}
+
+// returns true if c is a cell of the board, holes included
+func (m Model) onBoard(c Cell) bool {
+	size := len(m.currBoardState.board)
+	return c.Row >= 0 && c.Row < size && c.Col >= 0 && c.Col < size
+}
+
+// returns the cells of a set in reading order
+func sortedCells(set map[coordinate]bool) []Cell {
+	cells := make([]Cell, 0, len(set))
+	for c := range set {
+		cells = append(cells, Cell{Row: c.row, Col: c.col})
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Row != cells[j].Row {
+			return cells[i].Row < cells[j].Row
+		}
+		return cells[i].Col < cells[j].Col
+	})
+	return cells
+}
@@ -0,0 +1,212 @@
+package board
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// backtracking solver over a set of constraints
+type solver struct {
+	values      int // values go from 1 to values, the board size for everything but samurai
+	constraints []Constraint
+	rng         *rand.Rand // tries values in random order if set, used to fill new grids
+	limit       int        // stop after finding this many solutions
+	maxNodes    int        // give up after this many guesses, 0 for no limit
+
+	nodes     int
+	solutions int
+	first     grid
+	second    grid
+}
+
+/*
+counts the solutions of g under constraints, stopping once limit are found.
+Returns the number of solutions found and the first one, g is left unchanged.
+We always fill the empty cell with the fewest candidates next, which keeps
+the search small enough for killer puzzles with no given cells
+*/
+func solve(g grid, values int, constraints []Constraint, limit int) (int, grid) {
+	s := solver{values: values, constraints: constraints, limit: limit}
+	s.search(g.copyGrid())
+	return s.solutions, s.first
+}
+
+// returns a random completely filled copy of the empty grid g following constraints, or nil
+// if there is none or none was found in maxNodes guesses
+func fillGrid(g grid, values int, constraints []Constraint, rng *rand.Rand, maxNodes int) grid {
+	s := solver{values: values, constraints: constraints, rng: rng, limit: 1, maxNodes: maxNodes}
+	s.search(g.copyGrid())
+	return s.first
+}
+
+// returns true if g has exactly one solution, puzzles that take more than
+// maxNodes guesses to check count as not unique. If two solutions were found
+// they are returned so the caller can see where they differ
+func uniqueSolution(g grid, values int, constraints []Constraint, maxNodes int) (bool, grid, grid) {
+	s := solver{values: values, constraints: constraints, limit: 2, maxNodes: maxNodes}
+	s.search(g.copyGrid())
+	unique := s.solutions == 1 && (maxNodes == 0 || s.nodes < maxNodes)
+	return unique, s.first, s.second
+}
+
+func (s *solver) done() bool {
+	return s.solutions >= s.limit || (s.maxNodes > 0 && s.nodes >= s.maxNodes)
+}
+
+/*
+every cell keeps the values it can still hold as a sudoku.Candidates bitmask.
+Placing a value clears the candidates its constraints eliminate,
+so finding the empty cell with the fewest options is just counting bits.
+Rules that eliminations can't fully capture, like cage sums, are still
+checked with Valid before a value is placed and used to narrow down the
+placed cell's peers
+*/
+type candidateMasks []sudoku.Candidates
+
+// sets up the candidate masks for g, returns nil if a filled cell breaks a rule
+func (s *solver) initialMasks(g grid) candidateMasks {
+	size := len(g)
+	masks := make(candidateMasks, size*size)
+	for i := range masks {
+		masks[i] = sudoku.AllCandidates(s.values)
+	}
+	for i := range g {
+		for j := range g[i] {
+			if g[i][j] == -1 || g[i][j] == hole {
+				continue
+			}
+			if !validAt(s.constraints, g, coordinate{i, j}) {
+				return nil
+			}
+			s.eliminate(masks, size, coordinate{i, j}, g[i][j])
+		}
+	}
+	return masks
+}
+
+// clears every candidate ruled out by placing val at cell
+func (s *solver) eliminate(masks candidateMasks, size int, cell coordinate, val int8) {
+	masks[cell.row*size+cell.col] = 0
+	for _, c := range s.constraints {
+		for _, e := range c.Eliminations(cell, val) {
+			idx := e.cell.row*size + e.cell.col
+			masks[idx] = masks[idx].Remove(int(e.val))
+		}
+	}
+}
+
+// drops candidates of cell's empty peers that Valid rejects, this is how rules like
+// cage sums, which eliminations don't capture, narrow down the cells they touch.
+// Peer constraints are skipped, their eliminations already are the whole rule
+func (s *solver) refinePeers(g grid, masks candidateMasks, cell coordinate) {
+	size := len(g)
+	for _, c := range s.constraints {
+		if _, ok := c.(peerConstraint); ok {
+			continue
+		}
+		for _, peer := range c.Peers(cell) {
+			if g[peer.row][peer.col] != -1 {
+				continue
+			}
+			mask := &masks[peer.row*size+peer.col]
+			mask.Iterate(func(val int) {
+				g[peer.row][peer.col] = int8(val)
+				if !validAt(s.constraints, g, peer) {
+					*mask = mask.Remove(val)
+				}
+			})
+			g[peer.row][peer.col] = -1
+		}
+	}
+}
+
+func (s *solver) search(g grid) {
+	masks := s.initialMasks(g)
+	if masks == nil {
+		return
+	}
+	s.searchMasks(g, masks)
+}
+
+func (s *solver) searchMasks(g grid, masks candidateMasks) {
+	size := len(g)
+
+	// find the empty cell with the fewest candidates
+	var best coordinate
+	bestCount := s.values + 1
+	for i := range g {
+		for j := range g[i] {
+			if g[i][j] != -1 {
+				continue
+			}
+			count := masks[i*size+j].Count()
+			if count == 0 { // dead end
+				return
+			}
+			if count < bestCount {
+				best, bestCount = coordinate{i, j}, count
+			}
+		}
+	}
+
+	// no empty cells left, we have a solution
+	if bestCount == s.values+1 {
+		s.solutions++
+		if s.first == nil {
+			s.first = g.copyGrid()
+		} else if s.second == nil {
+			s.second = g.copyGrid()
+		}
+		return
+	}
+
+	vals := make([]int8, 0, bestCount)
+	masks[best.row*size+best.col].Iterate(func(val int) {
+		vals = append(vals, int8(val))
+	})
+	if s.rng != nil {
+		s.rng.Shuffle(len(vals), func(i, j int) {
+			vals[i], vals[j] = vals[j], vals[i]
+		})
+	}
+
+	next := make(candidateMasks, len(masks))
+	for _, val := range vals {
+		g[best.row][best.col] = val
+		if validAt(s.constraints, g, best) {
+			s.nodes++
+			copy(next, masks)
+			s.eliminate(next, size, best, val)
+			s.refinePeers(g, next, best)
+			s.searchMasks(g, next)
+		}
+		g[best.row][best.col] = -1
+		if s.done() {
+			return
+		}
+	}
+}
+
+// counts the solutions of the puzzle, stopping once limit are found, and returns
+// the first one written like Puzzle.Line, empty if there is none. Classic puzzles
+// are solved by the sudoku package
+func (p Puzzle) Solve(limit int) (int, string) {
+	if g, ok := p.sudokuGrid(); ok && limit > 0 {
+		first, err := g.Solve()
+		switch {
+		case errors.Is(err, sudoku.ErrNoSolution):
+			return 0, ""
+		case errors.Is(err, sudoku.ErrNotUnique):
+			return g.CountSolutions(limit), first.Format()
+		}
+		return 1, first.Format()
+	}
+
+	n, first := solve(p.game, p.size, p.constraints(), limit)
+	if first == nil {
+		return n, ""
+	}
+	return n, gridLine(first)
+}
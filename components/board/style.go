@@ -1,9 +1,11 @@
 package board
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -16,128 +18,855 @@ const (
 	BOLD_BORDER_COLOR    = lipgloss.Color("#F26419")
 	PENCIL_MARK_COLOR    = lipgloss.Color("#F77F00")
 	FINAL_VALUE_COLOR    = lipgloss.Color("#ffffff")
+	PEER_COLOR           = lipgloss.Color("#276FA0")
+	PEER_GIVEN_COLOR     = lipgloss.Color("#0E4470")
+	SAME_DIGIT_COLOR     = lipgloss.Color("#FFD23F")
+	CAGE_COLOR           = lipgloss.Color("#E0E0E0")
+	THERMO_COLOR         = lipgloss.Color("#A7C957")
+	ARROW_COLOR          = lipgloss.Color("#C77DFF")
+	KROPKI_COLOR         = lipgloss.Color("#FFFFFF")
+)
+
+// colorblind safe colors, picked from the Okabe-Ito palette so that the
+// cursor and selection differ in lightness as well as hue
+const (
+	CB_GIVEN_BASE_COLOR     = lipgloss.Color("#0B2545")
+	CB_NOT_GIVEN_BASE_COLOR = lipgloss.Color("#0072B2")
+	CB_WRONG_BASE_COLOR     = lipgloss.Color("#D55E00")
+	CB_SELECTED_COLOR       = lipgloss.Color("#56B4E9")
+	CB_CURRENT_COLOR        = lipgloss.Color("#F0E442")
+	CB_PENCIL_MARK_COLOR    = lipgloss.Color("#F0E442")
+	CB_FINAL_VALUE_COLOR    = lipgloss.Color("#ffffff")
+	CB_HIGHLIGHT_TEXT_COLOR = lipgloss.Color("#000000")
+	CB_PEER_COLOR           = lipgloss.Color("#005A8C")
+	CB_PEER_GIVEN_COLOR     = lipgloss.Color("#1C3F66")
+	CB_SAME_DIGIT_COLOR     = lipgloss.Color("#E69F00")
+)
+
+// cursor and selection backgrounds of the players of a shared board, by player number. Players
+// after the last get the colors again. Each player's own cursor is drawn in the usual colors
+var playerColors = []struct{ cursor, selected lipgloss.Color }{
+	{lipgloss.Color("#E76F51"), lipgloss.Color("#9C4A36")},
+	{lipgloss.Color("#8AC926"), lipgloss.Color("#5C8619")},
+	{lipgloss.Color("#C77DFF"), lipgloss.Color("#8554AA")},
+	{lipgloss.Color("#FFCA3A"), lipgloss.Color("#AA8727")},
+	{lipgloss.Color("#FF70A6"), lipgloss.Color("#AA4B6F")},
+	{lipgloss.Color("#2EC4B6"), lipgloss.Color("#1F8379")},
+}
+
+// returns the cursor color of player on a shared board
+func PlayerColor(player int) lipgloss.Color {
+	if player < 0 {
+		player = 0
+	}
+	return playerColors[player%len(playerColors)].cursor
+}
+
+// returns the glyph colorblind mode marks another player's cursor with, their number
+// written like a value, or + for numbers past the largest value
+func playerMarker(player int) string {
+	if player > 16 {
+		return "+"
+	}
+	return DigitString(int8(player))
+}
+
+// glyphs drawn in the padding of a cell in colorblind mode, so that no state
+// is shown by color alone. corners go top left, top right, bottom left, bottom right
+var (
+	cursorCorners   = [4]string{"┏", "┓", "┗", "┛"}
+	selectedCorners = [4]string{"•", "•", "•", "•"}
+	wrongMarker     = "✗"
+)
+
+// markers used in ascii mode, which has no color or text attributes at all. sides go left, right
+var (
+	asciiCursorSides     = [2]string{">", "<"}
+	asciiSelectedCorners = [4]string{"*", "*", "*", "*"}
+	asciiSelectedSides   = [2]string{"*", "*"}
+	asciiWrongSides      = [2]string{"!", "!"}
+	asciiGivenSides      = [2]string{"(", ")"}
+	asciiPlayerSides     = [2]string{"{", "}"}
+)
+
+// set of colors the board is drawn with
+type palette struct {
+	given, notGiven, wrong, selected, current lipgloss.Color
+	pencil, final                             lipgloss.Color
+	highlightText                             lipgloss.Color // text on the cursor and selected cells, "" keeps pencil/final colors
+	peer, peerGiven                           lipgloss.Color // backgrounds of cells that see the cursor cell
+	sameDigit                                 lipgloss.Color // values and pencil marks matching the cursor cell's value
+}
+
+var (
+	defaultPalette = palette{
+		given:     GIVEN_BASE_COLOR,
+		notGiven:  NOT_GIVEN_BASE_COLOR,
+		wrong:     WRONG_BASE_COLOR,
+		selected:  SELECTED_COLOR,
+		current:   CURRENT_COLOR,
+		pencil:    PENCIL_MARK_COLOR,
+		final:     FINAL_VALUE_COLOR,
+		peer:      PEER_COLOR,
+		peerGiven: PEER_GIVEN_COLOR,
+		sameDigit: SAME_DIGIT_COLOR,
+	}
+	colorblindPalette = palette{
+		given:         CB_GIVEN_BASE_COLOR,
+		notGiven:      CB_NOT_GIVEN_BASE_COLOR,
+		wrong:         CB_WRONG_BASE_COLOR,
+		selected:      CB_SELECTED_COLOR,
+		current:       CB_CURRENT_COLOR,
+		pencil:        CB_PENCIL_MARK_COLOR,
+		final:         CB_FINAL_VALUE_COLOR,
+		highlightText: CB_HIGHLIGHT_TEXT_COLOR,
+		peer:          CB_PEER_COLOR,
+		peerGiven:     CB_PEER_GIVEN_COLOR,
+		sameDigit:     CB_SAME_DIGIT_COLOR,
+	}
+)
+
+func getPalette(colorblind bool) palette {
+	if colorblind {
+		return colorblindPalette
+	}
+	return defaultPalette
+}
+
+// everything drawCell needs to know about a cell
+type cellState struct {
+	wrong, selected, current, given bool
+	value                           string // " " if the cell has no value
+	pencils                         sudoku.Candidates
+	peer                            bool // cell sees the cursor cell, only set when peer highlighting is on
+	sameDigit                       bool // cell value matches the cursor cell's value
+	matchPencil                     int8 // pencil mark to highlight, 0 for none
+	cage                            cageEdges
+	size                            int            // board size, pencil marks are laid out like its boxes
+	player                          lipgloss.Color // background of another player's cursor or selection, "" for none
+	playerCursor                    int            // number of the other player whose cursor is on the cell, 0 for none
+}
+
+// which sides of a cell are on the edge of its killer cage, and the cage
+// sum if this is the cell the sum is drawn in
+type cageEdges struct {
+	caged                    bool
+	top, bottom, left, right bool
+	sum                      string
+}
+
+// glyphs for drawing cage edges, box drawing and ascii
+var (
+	cageGlyphs = struct {
+		hor, vert                                  string
+		topLeft, topRight, bottomLeft, bottomRight string
+	}{"┄", "┆", "┌", "┐", "└", "┘"}
+	asciiCageGlyphs = struct {
+		hor, vert                                  string
+		topLeft, topRight, bottomLeft, bottomRight string
+	}{".", ":", ".", ".", "'", "'"}
 )
 
+// a single character of a cell and how to style it
+type glyph struct {
+	char      string
+	fg        lipgloss.Color
+	bold      bool
+	underline bool
+	reverse   bool
+}
+
+// a cell is a grid of 1 character glyphs, its size depends on the layout and board size. In the
+// full layout on a 9x9 board it is a 3x3 grid of 1 character slots with 1 character of padding
+// on left and right, so 3 rows of 9 characters. The padding characters are where colorblind
+// mode draws its markers
+type cellCanvas [][]glyph
+
+func newCellCanvas(rows, cols int) cellCanvas {
+	canvas := make(cellCanvas, rows)
+	for i := range canvas {
+		canvas[i] = make([]glyph, cols)
+	}
+	return canvas
+}
+
+// renders canvas row by row, styling runs of identical glyphs together
+func (c cellCanvas) render(s settings.Settings, background lipgloss.Color) string {
+	rows := make([]string, 0, len(c))
+	for _, row := range c {
+		rowString := ""
+		for j := 0; j < len(row); {
+			run := row[j].char
+			k := j + 1
+			for ; k < len(row) && row[k].sameStyle(row[j]); k++ {
+				run += row[k].char
+			}
+			rowString += s.NewStyle().
+				Foreground(row[j].fg).
+				Background(background).
+				Bold(row[j].bold).
+				Underline(row[j].underline).
+				Reverse(row[j].reverse).
+				Render(run)
+			j = k
+		}
+		rows = append(rows, rowString)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// renders canvas as plain text without any escape sequences
+func (c cellCanvas) renderPlain() string {
+	rows := make([]string, 0, len(c))
+	for _, row := range c {
+		rowString := ""
+		for _, g := range row {
+			rowString += g.char
+		}
+		rows = append(rows, rowString)
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (g glyph) sameStyle(o glyph) bool {
+	return g.fg == o.fg && g.bold == o.bold && g.underline == o.underline && g.reverse == o.reverse
+}
+
+// places glyphs at the four corners of canvas
+func (c cellCanvas) setCorners(corners [4]string, fg lipgloss.Color) {
+	last, lastCol := len(c)-1, len(c[0])-1
+	c[0][0] = glyph{char: corners[0], fg: fg, bold: true}
+	c[0][lastCol] = glyph{char: corners[1], fg: fg, bold: true}
+	c[last][0] = glyph{char: corners[2], fg: fg, bold: true}
+	c[last][lastCol] = glyph{char: corners[3], fg: fg, bold: true}
+}
+
+// places glyphs on the left and right edges of the middle row of canvas
+func (c cellCanvas) setSides(left, right string, fg lipgloss.Color) {
+	mid, lastCol := len(c)/2, len(c[0])-1
+	c[mid][0] = glyph{char: left, fg: fg, bold: true}
+	c[mid][lastCol] = glyph{char: right, fg: fg, bold: true}
+}
+
+// places glyphs on either side of the cell value in the middle of canvas
+func (c cellCanvas) setAroundValue(left, right string, fg lipgloss.Color) {
+	mid, center := len(c)/2, len(c[0])/2
+	c[mid][center-1] = glyph{char: left, fg: fg}
+	c[mid][center+1] = glyph{char: right, fg: fg}
+}
+
+/*
+draws the cage edges of a full cell in its padding characters, so pencil marks stay
+visible. The top and bottom edges go in the padding of the first and last rows, the
+left and right edges go in the first and last columns, and the cage sum goes down the
+left column from the top left corner. Cells have a row per box row, which is enough
+for every digit of the largest sum a cage can have on the board
+*/
+func (c cellCanvas) drawCage(edges cageEdges, fg lipgloss.Color, ascii bool) {
+	g := cageGlyphs
+	if ascii {
+		g = asciiCageGlyphs
+	}
+	last, lastCol := len(c)-1, len(c[0])-1
+	set := func(row, col int, char string) {
+		c[row][col] = glyph{char: char, fg: fg}
+	}
+
+	for j := 0; j <= lastCol; j++ {
+		if j%fullSlotWidth == fullSlotWidth/2 { // pencil mark or value
+			continue
+		}
+		if edges.top {
+			set(0, j, g.hor)
+		}
+		if edges.bottom {
+			set(last, j, g.hor)
+		}
+	}
+	for i := 0; i <= last; i++ {
+		if edges.left {
+			set(i, 0, g.vert)
+		}
+		if edges.right {
+			set(i, lastCol, g.vert)
+		}
+	}
+
+	if edges.top && edges.left {
+		set(0, 0, g.topLeft)
+	}
+	if edges.top && edges.right {
+		set(0, lastCol, g.topRight)
+	}
+	if edges.bottom && edges.left {
+		set(last, 0, g.bottomLeft)
+	}
+	if edges.bottom && edges.right {
+		set(last, lastCol, g.bottomRight)
+	}
+
+	for i, ch := range edges.sum {
+		if i > last {
+			break
+		}
+		c[i][0] = glyph{char: string(ch), fg: fg, bold: !ascii}
+	}
+}
+
+// applies fn to every glyph in canvas
+func (c cellCanvas) each(fn func(g *glyph)) {
+	for i := range c {
+		for j := range c[i] {
+			fn(&c[i][j])
+		}
+	}
+}
+
+// characters per pencil mark slot of a full cell, the mark in the middle and a
+// character of padding on either side
+const fullSlotWidth = 3
+
+// sizes of the pieces of the board in each layout
+type layoutMetrics struct {
+	cellWidth, cellHeight int
+	borderWidth           int // width of a vertical box border
+	headerHeight          int // error line and the blank lines under it
+	panelWidth            int // width of the side panel, 0 if the layout has none
+}
+
+/*
+returns the metrics of layout l for a board of size. Pencil marks are laid out
+like the board's boxes, so cells have a row per box row and a slot per box col.
+Full cells have 3 characters per slot, medium cells 1, and compact cells are a
+single row with the value. Medium cells are at least 3 characters wide, so ascii mode
+has room for its markers on either side of the value when boxes are 2 cells wide
+*/
+func getMetrics(l settings.Layout, size int) layoutMetrics {
+	rows, cols := boxShape(size)
+	switch l {
+	case settings.LayoutMedium:
+		width := cols
+		if width < 3 {
+			width = 3
+		}
+		return layoutMetrics{cellWidth: width, cellHeight: rows, borderWidth: 1, headerHeight: 2}
+	case settings.LayoutCompact:
+		return layoutMetrics{cellWidth: 3, cellHeight: 1, borderWidth: 1, headerHeight: 2, panelWidth: pencilPanelWidth(size)}
+	default:
+		return layoutMetrics{cellWidth: cols * fullSlotWidth, cellHeight: rows, borderWidth: 3, headerHeight: 3}
+	}
+}
+
+// returns the column a pencil mark is drawn in, the middle of slot col of cols slots
+// spread evenly over a cell width characters wide
+func slotColumn(col, cols, width int) int {
+	return (2*col + 1) * width / (2 * cols)
+}
+
+// returns the width of the compact layout's pencil panel, wide enough for its
+// title and a box row of pencil marks
+func pencilPanelWidth(size int) int {
+	_, cols := boxShape(size)
+	if w := 3 + 2*cols; w > 9 {
+		return w
+	}
+	return 9
+}
+
 var (
 
 	/*
-	   draws a full cell, which is a 3x3 grid of 1 character cells with 1 cell padding on left and right.
-	   this allows us to put pencil markings in each cell of the 3x3 grid.
+	   draws a cell as a grid of pencil mark slots laid out like the board's boxes, rows x cols
+	   slots spread evenly over width characters with the mark in the middle of its slot.
+	   A cell with a value only has the value, right in the middle of the cell
 	*/
-	drawFullCell = func(cellColor lipgloss.Color, cell string, pencils map[int8]bool) string {
-		cellString := ""
-		for i := 0; i < 3; i++ {
-			currRow := ""
-			for j := 0; j < 3; j++ {
-				// checks whether to render pencil marks or cell value
-				var valToRender string
-				var foregroundColor lipgloss.Color
-				if cell == " " { // cell not marked render, pencil marks
-					if pencils[int8(i*3+j+1)] {
-						valToRender = fmt.Sprintf("%d", i*3+j+1)
-					} else {
-						valToRender = " "
-					}
-					foregroundColor = PENCIL_MARK_COLOR
-				} else { // cell marked, dont render pencil marks, only render cell val on middle cell
-					if i == 1 && j == 1 {
-						valToRender = cell
-					} else {
-						valToRender = " "
-					}
-					foregroundColor = FINAL_VALUE_COLOR
-				}
+	drawPencilGrid = func(pencilColor, finalColor lipgloss.Color, cell string, pencils sudoku.Candidates, size, width int) cellCanvas {
+		rows, cols := boxShape(size)
+		canvas := newCellCanvas(rows, width)
+		if cell != " " { // cell marked, dont render pencil marks, only render cell val on middle cell
+			canvas.each(func(g *glyph) { *g = glyph{char: " ", fg: finalColor} })
+			canvas[rows/2][width/2].char = cell
+			return canvas
+		}
 
-				// creates cell string
-				currRow += lipgloss.NewStyle().
-					Padding(0, 1, 0, 1).
-					Foreground(foregroundColor).
-					Background(cellColor).
-					Render(valToRender)
-			}
-			// only add a new line on the first two rows of the cell
-			if i < 2 {
-				cellString += currRow + "\n"
-			} else {
-				cellString += currRow
+		// cell not marked, render pencil marks
+		canvas.each(func(g *glyph) { *g = glyph{char: " ", fg: pencilColor} })
+		pencils.Iterate(func(num int) {
+			if num <= size {
+				slot := num - 1
+				canvas[slot/cols][slotColumn(slot%cols, cols, width)].char = DigitString(int8(num))
 			}
+		})
+		return canvas
+	}
+
+	/*
+	   draws a full cell, which is a grid of 1 character cells with 1 cell padding on left and right,
+	   3x3 on a 9x9 board. this allows us to put pencil markings in each cell of the grid.
+	*/
+	drawFullCell = func(pencilColor, finalColor lipgloss.Color, cell string, pencils sudoku.Candidates, size int) cellCanvas {
+		return drawPencilGrid(pencilColor, finalColor, cell, pencils, size, getMetrics(settings.LayoutFull, size).cellWidth)
+	}
+
+	// draws a medium cell, the same pencil grid as a full cell without the padding
+	drawMediumCell = func(pencilColor, finalColor lipgloss.Color, cell string, pencils sudoku.Candidates, size int) cellCanvas {
+		return drawPencilGrid(pencilColor, finalColor, cell, pencils, size, getMetrics(settings.LayoutMedium, size).cellWidth)
+	}
+
+	// draws a compact cell, a single row with the cell value and no pencil marks
+	drawCompactCell = func(finalColor lipgloss.Color, cell string) cellCanvas {
+		canvas := newCellCanvas(1, 3)
+		canvas[0][0] = glyph{char: " ", fg: finalColor}
+		canvas[0][1] = glyph{char: cell, fg: finalColor}
+		canvas[0][2] = glyph{char: " ", fg: finalColor}
+		return canvas
+	}
+
+	// draws a cell that is not on the board, i.e. between the grids of a samurai puzzle, as blank space
+	drawHoleCell = func(lm layoutMetrics) string {
+		lines := make([]string, lm.cellHeight)
+		for i := range lines {
+			lines[i] = strings.Repeat(" ", lm.cellWidth)
 		}
-		return cellString
+		return strings.Join(lines, "\n")
 	}
 
-	// renders cell
-	drawCell = func(cellWrong, isSelected, isCurrCell, given bool, cell string, pencils map[int8]bool) string {
-		if isCurrCell { // cursor cell
-			return drawFullCell(CURRENT_COLOR, cell, pencils)
-		} else if isSelected { // highlighted cell that is not the cursor
-			return drawFullCell(SELECTED_COLOR, cell, pencils)
+	/*
+	   renders cell in the given layout. In colorblind mode the cursor, selection, wrong and given
+	   states are also marked with glyphs and text attributes, and other players' cursors with
+	   their number. Full cells have padding to draw markers in, medium cells are packed with
+	   pencil marks so the cursor and selection are shown with reverse video and underlines instead
+	*/
+	drawCell = func(c cellState, s settings.Settings, layout settings.Layout) string {
+		if s.ASCII {
+			return drawPlainCell(c, layout)
+		}
+
+		colorblind := s.Colorblind
+		p := getPalette(colorblind)
+
+		var cellColor lipgloss.Color
+		if c.current { // cursor cell
+			cellColor = p.current
+		} else if c.selected { // highlighted cell that is not the cursor
+			cellColor = p.selected
+		} else if c.player != "" { // another player's cursor or selection on a shared board
+			cellColor = c.player
 		} else { // base color cells
-			if given { // given cell
-				return drawFullCell(GIVEN_BASE_COLOR, cell, pencils)
-			} else if cellWrong { // wrong cell
-				return drawFullCell(WRONG_BASE_COLOR, cell, pencils)
+			if c.given && c.peer { // given cell seeing the cursor
+				cellColor = p.peerGiven
+			} else if c.given { // given cell
+				cellColor = p.given
+			} else if c.wrong { // wrong cell
+				cellColor = p.wrong
+			} else if c.peer { // modifiable cell seeing the cursor
+				cellColor = p.peer
 			} else { // modifiable cell
-				return drawFullCell(NOT_GIVEN_BASE_COLOR, cell, pencils)
+				cellColor = p.notGiven
+			}
+		}
+
+		pencilColor, finalColor := p.pencil, p.final
+		if (c.current || c.selected) && p.highlightText != "" {
+			pencilColor, finalColor = p.highlightText, p.highlightText
+		}
+
+		var canvas cellCanvas
+		switch layout {
+		case settings.LayoutMedium:
+			canvas = drawMediumCell(pencilColor, finalColor, c.value, c.pencils, c.size)
+		case settings.LayoutCompact:
+			canvas = drawCompactCell(finalColor, c.value)
+		default:
+			canvas = drawFullCell(pencilColor, finalColor, c.value, c.pencils, c.size)
+		}
+
+		// values and pencil marks matching the cursor cell's value
+		if c.sameDigit {
+			mid := &canvas[len(canvas)/2][len(canvas[0])/2]
+			mid.fg = p.sameDigit
+			mid.bold = true
+		}
+		if c.cage.caged && layout == settings.LayoutFull {
+			cageColor := CAGE_COLOR
+			if (c.current || c.selected) && p.highlightText != "" {
+				cageColor = p.highlightText
+			}
+			canvas.drawCage(c.cage, cageColor, false)
+		}
+
+		if c.matchPencil != 0 && c.value == " " && c.pencils.Has(int(c.matchPencil)) && layout != settings.LayoutCompact {
+			// pencil marks are laid out like the board's boxes, each in the middle of its slot
+			_, cols := boxShape(c.size)
+			slot := int(c.matchPencil - 1)
+			mark := &canvas[slot/cols][slotColumn(slot%cols, cols, len(canvas[0]))]
+			mark.fg = p.sameDigit
+			mark.bold = true
+			mark.underline = colorblind
+		}
+
+		if colorblind {
+			mid := &canvas[len(canvas)/2][len(canvas[0])/2]
+			markerColor := mid.fg
+			if c.given {
+				mid.bold = true
+			}
+			if c.wrong || c.sameDigit {
+				mid.underline = true
+			}
+			// peers underline their bottom row, so the cursor's row, column and box
+			// show as lines under the cells
+			if c.peer && !c.current && !c.selected {
+				bottom := canvas[len(canvas)-1]
+				for j := range bottom {
+					bottom[j].underline = true
+				}
+			}
+
+			switch layout {
+			case settings.LayoutMedium:
+				if c.wrong {
+					canvas.setSides(wrongMarker, wrongMarker, markerColor)
+				}
+				if c.playerCursor != 0 && !c.current && !c.selected {
+					marker := playerMarker(c.playerCursor)
+					canvas.setSides(marker, marker, markerColor)
+				}
+				if c.current {
+					canvas.each(func(g *glyph) { g.reverse = true })
+				} else if c.selected {
+					canvas.each(func(g *glyph) { g.underline = true })
+				}
+			case settings.LayoutCompact:
+				if c.current {
+					canvas.setSides("[", "]", markerColor)
+				} else if c.selected {
+					canvas.setSides(selectedCorners[0], selectedCorners[1], markerColor)
+				} else if c.playerCursor != 0 {
+					marker := playerMarker(c.playerCursor)
+					canvas.setSides(marker, marker, markerColor)
+				} else if c.wrong {
+					canvas.setSides(wrongMarker, wrongMarker, markerColor)
+				}
+			default:
+				if c.wrong {
+					canvas.setSides(wrongMarker, wrongMarker, markerColor)
+				}
+				if c.current {
+					canvas.setCorners(cursorCorners, markerColor)
+				} else if c.selected {
+					canvas.setCorners(selectedCorners, markerColor)
+				} else if c.playerCursor != 0 {
+					marker := playerMarker(c.playerCursor)
+					canvas.setCorners([4]string{marker, marker, marker, marker}, markerColor)
+				}
 			}
 		}
+
+		return canvas.render(s, cellColor)
 	}
 
-	// takes string with direction(vert, hor) and a rowString, rowString only needed
-	// for horizontal border
-	drawBorder = func(dir string, rowString string) string {
-		if dir == "vert" {
-			return drawVerticalBorder()
-		} else {
-			return drawHorizontalBorder(rowString)
-		}
-	}
-
-	// returns vertical border string for one cell
-	drawVerticalBorder = func() string {
-		border := lipgloss.NewStyle().
-			Padding(0, 1, 0, 1).
-			Foreground(BOLD_BORDER_COLOR).
-			Render("│")
-
-		return lipgloss.JoinVertical(lipgloss.Center, border, border, border)
-	}
-
-	// returns horizontal border string for one row
-	drawHorizontalBorder = func(rowString string) string {
-		rowWidth, _ := lipgloss.Size(rowString)
-		renderChar := "─"
-		/*
-		   the middle box border is one longer than the outside box borders
-		   since the middle box border has to meet the joint border on both sides,
-		   whereas the outside box borders have to meet the joint border only on
-		   one side.(each cell, including the borders, have a width of 3,
-		   as there are two padded cells on either side of the char)
-		   Thus, the outside borders have a length of boxWidth + 1  and the inside
-		   border has a length of boxWidth + 2
-		   rowWidth/3 - 1 = boxWidth + 1, as there are six cells that are not on the
-		   box (the border cells)
-		   ex:
-		   Each box is 27 cells wide, with 3 cells in between the boxes.
-		   Thus, the entire board is 87 cells wide. Outside box borders are 28 cells wide
-		   and the middle box border must be 29 cells wide.
-		*/
-		renderChar = strings.Repeat(renderChar, rowWidth/3-1)
-		middleBoxBorder := lipgloss.NewStyle().
-			Padding(0, 0, 0, 0).
-			Foreground(BOLD_BORDER_COLOR).
-			Render(renderChar + "─")
-		outsideBoxesBorders := lipgloss.NewStyle().
-			Padding(0, 0, 0, 0).
-			Foreground(BOLD_BORDER_COLOR).
-			Render(renderChar)
-		borderJoint := lipgloss.NewStyle().
-			Padding(0, 0, 0, 0).
-			Foreground(BOLD_BORDER_COLOR).
-			Render("┼")
-
-		return lipgloss.JoinHorizontal(lipgloss.Left,
-			outsideBoxesBorders, borderJoint,
-			middleBoxBorder, borderJoint,
-			outsideBoxesBorders)
+	/*
+	   renders cell for ascii mode, every state is marked with plain characters.
+	   Full cells mark the cursor on the outer edges, the selection in the corners
+	   and given or wrong values right next to the value. Medium and compact cells
+	   only have room on their sides, so only the most important state is marked:
+	   cursor, then selection, then other players' cursors, then wrong, then given.
+	   Peer and matching digit highlights are left out, every spot is taken
+	*/
+	drawPlainCell = func(c cellState, layout settings.Layout) string {
+		var canvas cellCanvas
+		switch layout {
+		case settings.LayoutMedium:
+			canvas = drawMediumCell("", "", c.value, c.pencils, c.size)
+		case settings.LayoutCompact:
+			canvas = drawCompactCell("", c.value)
+		default:
+			canvas = drawFullCell("", "", c.value, c.pencils, c.size)
+		}
+
+		if layout == settings.LayoutFull {
+			if c.cage.caged {
+				canvas.drawCage(c.cage, "", true)
+			}
+			if c.wrong {
+				canvas.setAroundValue(asciiWrongSides[0], asciiWrongSides[1], "")
+			} else if c.given {
+				canvas.setAroundValue(asciiGivenSides[0], asciiGivenSides[1], "")
+			}
+			if c.current {
+				canvas.setSides(asciiCursorSides[0], asciiCursorSides[1], "")
+			} else if c.selected {
+				canvas.setCorners(asciiSelectedCorners, "")
+			} else if c.playerCursor != 0 {
+				canvas.setSides(asciiPlayerSides[0], asciiPlayerSides[1], "")
+			}
+			return canvas.renderPlain()
+		}
+
+		if c.current {
+			canvas.setSides(asciiCursorSides[0], asciiCursorSides[1], "")
+		} else if c.selected {
+			canvas.setSides(asciiSelectedSides[0], asciiSelectedSides[1], "")
+		} else if c.playerCursor != 0 {
+			canvas.setSides(asciiPlayerSides[0], asciiPlayerSides[1], "")
+		} else if c.wrong {
+			canvas.setSides(asciiWrongSides[0], asciiWrongSides[1], "")
+		} else if c.given {
+			canvas.setSides(asciiGivenSides[0], asciiGivenSides[1], "")
+		}
+		return canvas.renderPlain()
+	}
+
+	// draws the pencil marks of the cursor cell for the compact layout, which has no room
+	// for them inside the cells
+	drawPencilPanel = func(pencils sudoku.Candidates, size int, s settings.Settings) string {
+		rows, cols := boxShape(size)
+		lines := []string{"pencils"}
+		for i := 0; i < rows; i++ {
+			marks := make([]string, cols)
+			for j := 0; j < cols; j++ {
+				if num := i*cols + j + 1; pencils.Has(num) {
+					marks[j] = DigitString(int8(num))
+				} else if s.ASCII {
+					marks[j] = "."
+				} else {
+					marks[j] = "·"
+				}
+			}
+			lines = append(lines, strings.Join(marks, " "))
+		}
+		style := s.NewStyle().
+			Width(pencilPanelWidth(size)).
+			PaddingLeft(2)
+		if !s.ASCII {
+			style = style.Foreground(PENCIL_MARK_COLOR)
+		}
+		return style.Render(strings.Join(lines, "\n"))
+	}
+
+	// returns the style for box borders, uncolored in ascii mode
+	borderStyle = func(s settings.Settings) lipgloss.Style {
+		if s.ASCII {
+			return s.NewStyle()
+		}
+		return s.NewStyle().Foreground(BOLD_BORDER_COLOR)
+	}
+
+	// returns vertical border string for one cell, padded on both sides in the full layout.
+	// Blank if the cells on either side are in the same region. A clue mark between the
+	// cells goes on the middle line
+	drawVerticalBorder = func(edge bool, mark clueMark, lm layoutMetrics, s settings.Settings) string {
+		style := borderStyle(s)
+		pad := (lm.borderWidth - 1) / 2
+		if pad > 0 {
+			style = style.Padding(0, pad, 0, pad)
+		}
+		renderChar := " "
+		if edge && s.ASCII {
+			renderChar = "|"
+		} else if edge {
+			renderChar = "│"
+		}
+		border := style.Render(renderChar)
+
+		lines := make([]string, lm.cellHeight)
+		for i := range lines {
+			lines[i] = border
+		}
+		if mark.char != "" {
+			padding := strings.Repeat(" ", pad)
+			lines[len(lines)/2] = padding + mark.render(s) + padding
+		}
+		return lipgloss.JoinVertical(lipgloss.Center, lines...)
 	}
+
+	/*
+	   returns horizontal border string for one row. edges has an entry per column, true where
+	   the cells above and below are in different regions. joints has an entry for every
+	   column with a vertical border after it, the joint is drawn in the middle of that
+	   border and the padding on either side continues the edge of the column next to it.
+	   marks and jointMarks are clue marks by column, drawn in the middle of the column's
+	   edge and in place of the joint.
+	   ex:
+	   In the full layout on a 9x9 board each cell is 9 characters wide and vertical borders
+	   are 3 wide, so for classic boxes the border is 28 characters of line, a joint, 29 characters
+	   of line, a joint, and 28 characters of line. 87 in total, the width of the board
+	*/
+	drawHorizontalBorder = func(edges []bool, marks map[int]clueMark, joints map[int]borderJoint, jointMarks map[int]clueMark, lm layoutMetrics, s settings.Settings) string {
+		lineChar := "─"
+		if s.ASCII {
+			lineChar = "-"
+		}
+		line := func(edge bool, width int) string {
+			if edge {
+				return strings.Repeat(lineChar, width)
+			}
+			return strings.Repeat(" ", width)
+		}
+
+		// border characters are collected and rendered together, clue marks in their own style
+		var b strings.Builder
+		border := ""
+		putMark := func(mark clueMark) {
+			if border != "" {
+				b.WriteString(borderStyle(s).Render(border))
+				border = ""
+			}
+			b.WriteString(mark.render(s))
+		}
+
+		pad := (lm.borderWidth - 1) / 2
+		for j, edge := range edges {
+			if mark, ok := marks[j]; ok {
+				border += line(edge, lm.cellWidth/2)
+				putMark(mark)
+				border += line(edge, lm.cellWidth-lm.cellWidth/2-1)
+			} else {
+				border += line(edge, lm.cellWidth)
+			}
+			if joint, ok := joints[j]; ok {
+				border += line(joint.left, pad)
+				if mark, ok := jointMarks[j]; ok {
+					putMark(mark)
+				} else {
+					border += joint.glyph(s.ASCII)
+				}
+				border += line(joint.right, pad)
+			}
+		}
+		if border != "" {
+			b.WriteString(borderStyle(s).Render(border))
+		}
+		return b.String()
+	}
+)
+
+// the region borders meeting where a horizontal and a vertical border line cross
+type borderJoint struct {
+	up, down, left, right bool
+}
+
+// box drawing characters for every combination of borders meeting at a joint
+var jointGlyphs = map[borderJoint]string{
+	{}:                                    " ",
+	{up: true}:                            "╵",
+	{down: true}:                          "╷",
+	{left: true}:                          "╴",
+	{right: true}:                         "╶",
+	{up: true, down: true}:                "│",
+	{left: true, right: true}:             "─",
+	{up: true, right: true}:               "└",
+	{up: true, left: true}:                "┘",
+	{down: true, right: true}:             "┌",
+	{down: true, left: true}:              "┐",
+	{up: true, down: true, right: true}:   "├",
+	{up: true, down: true, left: true}:    "┤",
+	{down: true, left: true, right: true}: "┬",
+	{up: true, left: true, right: true}:   "┴",
+	{up: true, down: true, left: true, right: true}: "┼",
+}
+
+func (j borderJoint) glyph(ascii bool) string {
+	if !ascii {
+		return jointGlyphs[j]
+	}
+	vert, hor := j.up || j.down, j.left || j.right
+	switch {
+	case vert && hor:
+		return "+"
+	case vert:
+		return "|"
+	case hor:
+		return "-"
+	}
+	return " "
+}
+
+// a clue drawn in the gap between cells, like a kropki dot or a step of a thermometer
+type clueMark struct {
+	char, asciiChar string
+	fg              lipgloss.Color
+}
+
+func (c clueMark) render(s settings.Settings) string {
+	if s.ASCII {
+		return c.asciiChar
+	}
+	return s.NewStyle().Foreground(c.fg).Bold(true).Render(c.char)
+}
+
+type gapKind int
+
+const (
+	gapRight gapKind = iota // between cell and the cell right of it
+	gapDown                 // between cell and the cell under it
+	gapJoint                // where the gaps right of and under cell cross, between diagonal neighbors
+)
+
+// a gap between cells that a clue mark can go in
+type gap struct {
+	cell coordinate
+	kind gapKind
+}
+
+// returns the gap between neighboring cells a and b, diagonal neighbors meet at a joint
+func gapBetween(a, b coordinate) gap {
+	top, left := a.row, a.col
+	if b.row < top {
+		top = b.row
+	}
+	if b.col < left {
+		left = b.col
+	}
+	switch {
+	case a.row == b.row:
+		return gap{coordinate{top, left}, gapRight}
+	case a.col == b.col:
+		return gap{coordinate{top, left}, gapDown}
+	default:
+		return gap{coordinate{top, left}, gapJoint}
+	}
+}
+
+// marks for each step along a path by its direction, unicode and ascii. Thermometer marks
+// open towards the bigger value like < and >, arrow marks point away from the circle
+var (
+	thermoSteps = map[coordinate][2]string{
+		{0, 1}: {"<", "<"}, {0, -1}: {">", ">"}, {1, 0}: {"∧", "^"}, {-1, 0}: {"∨", "v"},
+		{1, 1}: {"↘", "\\"}, {1, -1}: {"↙", "/"}, {-1, 1}: {"↗", "/"}, {-1, -1}: {"↖", "\\"},
+	}
+	arrowSteps = map[coordinate][2]string{
+		{0, 1}: {"→", "-"}, {0, -1}: {"←", "-"}, {1, 0}: {"↓", "|"}, {-1, 0}: {"↑", "|"},
+		{1, 1}: {"↘", "\\"}, {1, -1}: {"↙", "/"}, {-1, 1}: {"↗", "/"}, {-1, -1}: {"↖", "\\"},
+	}
+	whiteDot = clueMark{char: "○", asciiChar: "o", fg: KROPKI_COLOR}
+	blackDot = clueMark{char: "●", asciiChar: "@", fg: KROPKI_COLOR}
 )
+
+// returns the marks of all thermometers, arrows and kropki dots of p by the gap they go in
+func clueMarks(p Puzzle) map[gap]clueMark {
+	marks := make(map[gap]clueMark)
+	addPath := func(path []coordinate, steps map[coordinate][2]string, fg lipgloss.Color) {
+		for i := 1; i < len(path); i++ {
+			a, b := path[i-1], path[i]
+			step := steps[coordinate{b.row - a.row, b.col - a.col}]
+			marks[gapBetween(a, b)] = clueMark{char: step[0], asciiChar: step[1], fg: fg}
+		}
+	}
+
+	for _, path := range p.thermos {
+		addPath(path, thermoSteps, THERMO_COLOR)
+	}
+	for _, path := range p.arrows {
+		addPath(path, arrowSteps, ARROW_COLOR)
+	}
+	for _, d := range p.dots {
+		if d.black {
+			marks[gapBetween(d.a, d.b)] = blackDot
+		} else {
+			marks[gapBetween(d.a, d.b)] = whiteDot
+		}
+	}
+	return marks
+}
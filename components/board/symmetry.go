@@ -0,0 +1,64 @@
+package board
+
+/*
+symmetries the givens of generated puzzles can be laid out with, a symmetric
+layout looks the same after its moves:
+
+	none        givens anywhere
+	rotational  turned 180° around the center
+	horizontal  mirrored top to bottom
+	vertical    mirrored left to right
+	diagonal    mirrored over the diagonal from the top left to the bottom right
+	dihedral    turned 90° or mirrored any of these ways
+*/
+var Symmetries = []string{"none", "rotational", "horizontal", "vertical", "diagonal", "dihedral"}
+
+func rotate180(c coordinate, n int) coordinate  { return coordinate{n - 1 - c.row, n - 1 - c.col} }
+func rotate90(c coordinate, n int) coordinate   { return coordinate{c.col, n - 1 - c.row} }
+func mirrorRows(c coordinate, n int) coordinate { return coordinate{n - 1 - c.row, c.col} }
+func mirrorCols(c coordinate, n int) coordinate { return coordinate{c.row, n - 1 - c.col} }
+func transpose(c coordinate, n int) coordinate  { return coordinate{c.col, c.row} }
+
+// the moves that map a symmetric layout onto itself, for each symmetry. The
+// cells a cell can be moved to by any mix of them all go together, see symmetryOrbits
+var symmetryMoves = map[string][]func(cell coordinate, n int) coordinate{
+	"none":       nil,
+	"rotational": {rotate180},
+	"horizontal": {mirrorRows},
+	"vertical":   {mirrorCols},
+	"diagonal":   {transpose},
+	"dihedral":   {rotate90, transpose},
+}
+
+// returns true if name is one of Symmetries
+func IsSymmetry(name string) bool {
+	_, ok := symmetryMoves[name]
+	return ok
+}
+
+// splits cells of an n x n board into the groups symmetry maps onto each other, every
+// cell is in one group. No symmetry, or an unknown one, puts every cell on its own.
+// Boards with holes must be symmetric themselves, like samurai boards are
+func symmetryOrbits(cells []coordinate, n int, symmetry string) [][]coordinate {
+	moves := symmetryMoves[symmetry]
+	seen := make(map[coordinate]bool)
+	var orbits [][]coordinate
+	for _, cell := range cells {
+		if seen[cell] {
+			continue
+		}
+		seen[cell] = true
+		orbit := []coordinate{cell}
+		for i := 0; i < len(orbit); i++ {
+			for _, move := range moves {
+				next := move(orbit[i], n)
+				if !seen[next] {
+					seen[next] = true
+					orbit = append(orbit, next)
+				}
+			}
+		}
+		orbits = append(orbits, orbit)
+	}
+	return orbits
+}
@@ -0,0 +1,208 @@
+package coop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const LEFT_COLOR = lipgloss.Color("#5C5C5C")
+
+// this is a tea.Msg type for an edit from the server, the app model applies it to the board
+type EditReceived struct {
+	Edit board.Edit
+}
+
+// a cursor or leave message from the server
+type receivedMsg struct {
+	msg Message
+}
+
+// the connection to the server broke
+type disconnectedMsg struct {
+	err error
+}
+
+/*
+Side panel listing the players of a coop game, in the colors of their cursors,
+and the player's side of the protocol. The app model passes the board's edits
+on with SendEdit and its cursor with SetCursor, and applies the edits that come
+back as EditReceived messages. The zero Model is not in a game and does nothing
+*/
+type Model struct {
+	conn     *Conn
+	player   int // our player number
+	settings settings.Settings
+
+	sent    board.PlayerCursor // our cursor as last sent, we only send changes
+	players map[int]board.PlayerCursor
+	left    error // why we lost the server, nil while connected
+}
+
+func NewModel(conn *Conn, player int, s settings.Settings) Model {
+	return Model{
+		conn:     conn,
+		player:   player,
+		settings: s,
+		sent:     board.PlayerCursor{Cursor: board.Cell{Row: -1, Col: -1}},
+		players:  make(map[int]board.PlayerCursor),
+	}
+}
+
+// returns true if we are in a coop game, even if we lost the server
+func (m Model) Active() bool {
+	return m.conn != nil
+}
+
+// returns true if we are in a coop game and connected to its server
+func (m Model) Connected() bool {
+	return m.Active() && m.left == nil
+}
+
+// starts listening for the server's messages
+func (m Model) Init() tea.Cmd {
+	if !m.Active() {
+		return nil
+	}
+	return m.receive()
+}
+
+// waits for the next message from the server. Messages from player numbers below 0
+// are dropped, the server is 0 and players count from 1
+func (m Model) receive() tea.Cmd {
+	conn := m.conn
+	return func() tea.Msg {
+		for {
+			msg, err := conn.Receive()
+			switch {
+			case err != nil:
+				return disconnectedMsg{err}
+			case msg.Player < 0:
+				continue
+			case msg.Type == typeEdit && msg.Edit != nil:
+				return EditReceived{Edit: *msg.Edit}
+			}
+			return receivedMsg{msg}
+		}
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case EditReceived:
+		return m, m.receive()
+
+	case receivedMsg:
+		switch msg.msg.Type {
+		case typeCursor:
+			if msg.msg.Cursor != nil {
+				m.players[msg.msg.Player] = board.PlayerCursor{
+					Player:   msg.msg.Player,
+					Cursor:   *msg.msg.Cursor,
+					Selected: msg.msg.Selected,
+				}
+			}
+		case typeLeave:
+			delete(m.players, msg.msg.Player)
+		}
+		return m, m.receive()
+
+	case disconnectedMsg:
+		m.left = msg.err
+		m.conn.Close()
+		m.players = make(map[int]board.PlayerCursor)
+	}
+
+	return m, nil
+}
+
+// sends msg to the server, if it can't be sent the server is treated as gone
+func (m *Model) send(msg Message) {
+	if !m.Connected() {
+		return
+	}
+	if err := m.conn.Send(msg); err != nil {
+		m.left = err
+		m.conn.Close()
+		m.players = make(map[int]board.PlayerCursor)
+	}
+}
+
+// sends an edit of our board to the server
+func (m *Model) SendEdit(e board.Edit) {
+	m.send(Message{Type: typeEdit, Edit: &e})
+}
+
+// sends our cursor and selected cells to the server if they changed
+func (m *Model) SetCursor(cursor board.Cell, selected []board.Cell) {
+	if cursor == m.sent.Cursor && sameCells(selected, m.sent.Selected) {
+		return
+	}
+	m.sent = board.PlayerCursor{Player: m.player, Cursor: cursor, Selected: selected}
+	m.send(Message{Type: typeCursor, Cursor: &cursor, Selected: selected})
+}
+
+// returns the other players' cursors
+func (m Model) Players() []board.PlayerCursor {
+	players := make([]board.PlayerCursor, 0, len(m.players))
+	for _, p := range m.players {
+		players = append(players, p)
+	}
+	return players
+}
+
+// replaces the panel's display settings
+func (m *Model) SetSettings(s settings.Settings) {
+	m.settings = s
+}
+
+func sameCells(a, b []board.Cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Model) View() string {
+	if !m.Active() {
+		return ""
+	}
+
+	rows := []string{"players"}
+	if m.left != nil {
+		left := "server gone"
+		if !m.settings.ASCII {
+			left = m.settings.NewStyle().Foreground(LEFT_COLOR).Faint(true).Render(left)
+		}
+		return strings.Join(append(rows, left), "\n")
+	}
+
+	ids := []int{m.player}
+	for id := range m.players {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		name := fmt.Sprintf("player %d", id)
+		if id == m.player {
+			name += " (you)"
+		}
+		// our own cursor is drawn in the usual colors, so we only color the others
+		if !m.settings.ASCII && id != m.player {
+			name = m.settings.NewStyle().Foreground(board.PlayerColor(id)).Render(name)
+		}
+		rows = append(rows, name)
+	}
+	return strings.Join(rows, "\n")
+}
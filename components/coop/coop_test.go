@@ -0,0 +1,177 @@
+package coop
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+)
+
+// how long a test waits for a message that should arrive
+const receiveTimeout = 5 * time.Second
+
+// serves a seeded 4x4 game on the loopback interface, returns its address
+func serve(t *testing.T) string {
+	t.Helper()
+	s, err := NewServer(board.GameOptions{Size: 4, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.Serve(l)
+	return l.Addr().String()
+}
+
+// a player's end of the game: its connection and the board it keeps in step
+type client struct {
+	conn   *Conn
+	player int
+	board  board.Model
+}
+
+func join(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, opts, player, err := Join(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	b, err := board.NewModel(opts, settings.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{conn: conn, player: player, board: b}
+}
+
+// returns the next message from the server, failing the test if none comes in time
+func (c *client) receive(t *testing.T) Message {
+	t.Helper()
+	received := make(chan Message, 1)
+	failed := make(chan error, 1)
+	go func() {
+		msg, err := c.conn.Receive()
+		if err != nil {
+			failed <- err
+			return
+		}
+		received <- msg
+	}()
+	select {
+	case msg := <-received:
+		return msg
+	case err := <-failed:
+		t.Fatalf("player %d: %v", c.player, err)
+	case <-time.After(receiveTimeout):
+		t.Fatalf("player %d: no message from the server", c.player)
+	}
+	return Message{}
+}
+
+// receives the next message, which has to be an edit from player, and applies it to
+// the client's board the way the app model does
+func (c *client) receiveEdit(t *testing.T, player int) board.Edit {
+	t.Helper()
+	msg := c.receive(t)
+	if msg.Type != typeEdit || msg.Edit == nil || msg.Player != player {
+		t.Fatalf("player %d got %+v, want an edit from player %d", c.player, msg, player)
+	}
+	c.board.ApplyEdit(*msg.Edit)
+	return *msg.Edit
+}
+
+// returns the first empty cells of b
+func emptyCells(b board.Model, n int) []board.Cell {
+	var cells []board.Cell
+	for _, c := range b.Contents() {
+		if c.Val == -1 && len(cells) < n {
+			cells = append(cells, c.Cell)
+		}
+	}
+	return cells
+}
+
+func valueOf(b board.Model, cell board.Cell) int8 {
+	for _, c := range b.Contents() {
+		if c.Cell == cell {
+			return c.Val
+		}
+	}
+	return -1
+}
+
+func TestSharedBoard(t *testing.T) {
+	addr := serve(t)
+	one, two := join(t, addr), join(t, addr)
+	if one.player != 1 || two.player != 2 {
+		t.Fatalf("players %d and %d, want 1 and 2", one.player, two.player)
+	}
+	cells := emptyCells(one.board, 2)
+	a, b := cells[0], cells[1]
+
+	// cursors go to the other players only
+	if err := one.conn.Send(Message{Type: typeCursor, Cursor: &a, Selected: []board.Cell{a}}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := two.receive(t); msg.Type != typeCursor || msg.Player != 1 || *msg.Cursor != a {
+		t.Fatalf("player 2 got %+v, want player 1's cursor on %s", msg, a)
+	}
+
+	// edits go to everyone, the sender included, in the order the server applied them
+	if err := one.conn.Send(Message{Type: typeEdit, Edit: &board.Edit{Op: board.EditSet, Cells: []board.Cell{a}, Val: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	one.receiveEdit(t, 1)
+	two.receiveEdit(t, 1)
+	if err := two.conn.Send(Message{Type: typeEdit, Edit: &board.Edit{Op: board.EditSet, Cells: []board.Cell{b}, Val: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	one.receiveEdit(t, 2)
+	two.receiveEdit(t, 2)
+
+	// player 1's undo takes back their own edit and leaves player 2's alone
+	if err := one.conn.Send(Message{Type: typeEdit, Edit: &board.Edit{Op: board.EditUndo}}); err != nil {
+		t.Fatal(err)
+	}
+	if e := one.receiveEdit(t, 1); e.Op != board.EditRestore || len(e.Contents) != 1 || e.Contents[0].Cell != a {
+		t.Fatalf("undo sent %+v, want a restore of %s", e, a)
+	}
+	two.receiveEdit(t, 1)
+
+	for _, c := range []*client{one, two} {
+		if got := valueOf(c.board, a); got != -1 {
+			t.Errorf("player %d: %s holds %d after the undo, want it empty", c.player, a, got)
+		}
+		if got := valueOf(c.board, b); got != 2 {
+			t.Errorf("player %d: %s holds %d, want player 2's 2", c.player, b, got)
+		}
+	}
+	if !reflect.DeepEqual(one.board.Contents(), two.board.Contents()) {
+		t.Error("the players' boards differ")
+	}
+
+	// a player joining now gets the board as it is
+	three := join(t, addr)
+	three.receiveEdit(t, 0)
+	if msg := three.receive(t); msg.Type != typeCursor || msg.Player != 1 {
+		t.Fatalf("player 3 got %+v, want player 1's cursor", msg)
+	}
+	if !reflect.DeepEqual(three.board.Contents(), one.board.Contents()) {
+		t.Error("the board of a player who joined late differs")
+	}
+}
+
+func TestLeave(t *testing.T) {
+	addr := serve(t)
+	one, two := join(t, addr), join(t, addr)
+	two.conn.Close()
+	if msg := one.receive(t); msg.Type != typeLeave || msg.Player != 2 {
+		t.Fatalf("player 1 got %+v, want player 2 leaving", msg)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Package coop lets several players solve one board together over TCP. A server
+holds the authoritative board, every player sends it their edits and cursor
+moves, and the server applies the edits one at a time and sends each one to
+every player, the sender included. Every board starts from the same puzzle and
+applies the same edits in the same order, so all boards stay the same. When two
+players edit the same cell at once, the edit the server got last wins.
+
+Undo and redo are per player. The server keeps each player's history, and undoing
+puts back the cells the player's last edit changed, leaving alone any cell
+another player has changed since.
+
+The protocol is one JSON object per line, every object has a type. Fields a
+message doesn't use are left out. Players are numbered from 1. Server to player:
+
+	{"type":"start","player":2,"seed":42,"mode":2,"size":9,"killer":true,"variants":["diagonal"],"symmetry":"rotational"}
+	    the first message after joining, the player's number and the game options
+	    every board is generated from, see board.GameOptions
+	{"type":"edit","player":1,"edit":{"op":"set","cells":[{"row":0,"col":3}],"val":5}}
+	    an edit in the order the server applied it, see board.Edit. Undo and redo are
+	    sent as restores, and a player joining a game in progress first gets a
+	    restore from player 0 with every cell that has a value or pencil marks
+	{"type":"cursor","player":1,"cursor":{"row":0,"col":3},"selected":[{"row":0,"col":3}]}
+	    another player's cursor and selected cells
+	{"type":"leave","player":1}
+	    another player left
+
+Player to server:
+
+	{"type":"edit","edit":{"op":"pencil","cells":[{"row":4,"col":4}],"val":7}}
+	    a set, pencil, delete, undo or redo of the player's selected cells
+	{"type":"cursor","cursor":{"row":0,"col":3},"selected":[{"row":0,"col":3}]}
+	    the player moved their cursor
+
+Messages of unknown types are ignored.
+*/
+package coop
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/wire"
+)
+
+// message types, see the package comment
+const (
+	typeStart  = "start"
+	typeEdit   = "edit"
+	typeCursor = "cursor"
+	typeLeave  = "leave"
+)
+
+// a message of the coop protocol, see the package comment for which fields each type uses
+type Message struct {
+	Type   string `json:"type"`
+	Player int    `json:"player,omitempty"`
+
+	board.GameOptions // the game of a start message
+
+	Edit *board.Edit `json:"edit,omitempty"`
+
+	Cursor   *board.Cell  `json:"cursor,omitempty"`
+	Selected []board.Cell `json:"selected,omitempty"`
+}
+
+// a player's connection to the server
+type Conn struct {
+	wire *wire.Conn
+}
+
+func (c *Conn) Send(msg Message) error {
+	return c.wire.Send(msg)
+}
+
+// blocks until the next message arrives
+func (c *Conn) Receive() (Message, error) {
+	var msg Message
+	err := c.wire.Receive(&msg)
+	return msg, err
+}
+
+func (c *Conn) Close() error {
+	return c.wire.Close()
+}
+
+// joins the game served at addr, returns the game options and our player number
+func Join(addr string) (*Conn, board.GameOptions, int, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, board.GameOptions{}, 0, err
+	}
+	conn := &Conn{wire: wire.NewConn(c)}
+
+	msg, err := conn.Receive()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, 0, err
+	}
+	opts, err := msg.options()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, 0, err
+	}
+	return conn, opts, msg.Player, nil
+}
+
+// returns the game options of a start message
+func (msg Message) options() (board.GameOptions, error) {
+	if msg.Type != typeStart {
+		return board.GameOptions{}, fmt.Errorf("server sent %q, want %q", msg.Type, typeStart)
+	}
+	if msg.Player < 1 {
+		return board.GameOptions{}, fmt.Errorf("server sent player %d", msg.Player)
+	}
+	if err := msg.GameOptions.Validate(); err != nil {
+		return board.GameOptions{}, fmt.Errorf("server sent a game we can't play: %w", err)
+	}
+	return msg.GameOptions, nil
+}
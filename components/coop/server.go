@@ -0,0 +1,227 @@
+package coop
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	"github.com/Alex-Merrill/sudoku-tui/components/wire"
+)
+
+// messages queued for a player before they are too far behind and get cut off
+const outgoing = 256
+
+// the authoritative board of a coop game and the players connected to it
+type Server struct {
+	opts board.GameOptions
+
+	mu      sync.Mutex // held while handling a message, so messages are handled one at a time
+	board   board.Model
+	players map[int]*player
+	last    int // number of the last player to join
+}
+
+type player struct {
+	conn       *wire.Conn
+	out        chan Message // messages waiting to be written, see write
+	cursor     *Message     // last cursor message, sent to players who join later
+	undo, redo []change
+}
+
+// the cells an edit changed, as they were before and after it
+type change struct {
+	before, after []board.CellContents
+}
+
+// makes a server for a game of opts. opts gets a random seed if it has none,
+// so every player generates the same puzzle
+func NewServer(opts board.GameOptions) (*Server, error) {
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	b, err := board.NewModel(opts, settings.Settings{})
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		opts:    opts,
+		board:   b,
+		players: make(map[int]*player),
+	}, nil
+}
+
+// accepts players on l until it is closed
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(wire.NewConn(c))
+	}
+}
+
+// plays one player's connection until they leave
+func (s *Server) handle(conn *wire.Conn) {
+	s.mu.Lock()
+	s.last++
+	id := s.last
+	p := &player{conn: conn, out: make(chan Message, outgoing)}
+	s.players[id] = p
+	s.welcome(id)
+	s.mu.Unlock()
+	go p.write()
+
+	for {
+		var msg Message
+		if err := conn.Receive(&msg); err != nil {
+			break
+		}
+		s.mu.Lock()
+		s.handleMessage(id, msg)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	conn.Close()
+	close(p.out)
+	delete(s.players, id)
+	s.broadcast(Message{Type: typeLeave, Player: id}, id)
+	s.mu.Unlock()
+}
+
+// sends a new player the game, the board as it is now and everyone's cursors
+func (s *Server) welcome(id int) {
+	s.send(id, Message{Type: typeStart, Player: id, GameOptions: s.opts})
+
+	var filled []board.CellContents
+	for _, c := range s.board.Contents() {
+		if c.Val != -1 || len(c.Pencils) > 0 {
+			filled = append(filled, c)
+		}
+	}
+	if len(filled) > 0 {
+		s.send(id, Message{Type: typeEdit, Edit: &board.Edit{Op: board.EditRestore, Contents: filled}})
+	}
+
+	for other, p := range s.players {
+		if other != id && p.cursor != nil {
+			s.send(id, *p.cursor)
+		}
+	}
+}
+
+func (s *Server) handleMessage(id int, msg Message) {
+	p := s.players[id]
+	switch msg.Type {
+	case typeCursor:
+		if msg.Cursor == nil {
+			return
+		}
+		p.cursor = &Message{Type: typeCursor, Player: id, Cursor: msg.Cursor, Selected: msg.Selected}
+		s.broadcast(*p.cursor, id)
+
+	case typeEdit:
+		if msg.Edit == nil {
+			return
+		}
+		switch msg.Edit.Op {
+		case board.EditSet, board.EditPencil, board.EditDelete:
+			e := board.Edit{Op: msg.Edit.Op, Cells: msg.Edit.Cells, Val: msg.Edit.Val}
+			if c := s.apply(e); len(c.after) > 0 {
+				p.undo = append(p.undo, c)
+				p.redo = nil
+				s.broadcast(Message{Type: typeEdit, Player: id, Edit: &e}, 0)
+			}
+		case board.EditUndo:
+			s.revert(id, &p.undo, &p.redo)
+		case board.EditRedo:
+			s.revert(id, &p.redo, &p.undo)
+		}
+	}
+}
+
+// applies e to the board and returns the cells it changed
+func (s *Server) apply(e board.Edit) change {
+	before := s.board.Contents()
+	s.board.ApplyEdit(e)
+	after := s.board.Contents()
+
+	var c change
+	for i := range before {
+		if !before[i].Equal(after[i]) {
+			c.before = append(c.before, before[i])
+			c.after = append(c.after, after[i])
+		}
+	}
+	return c
+}
+
+/*
+takes the last change off from and puts its cells back the way they were before it,
+as a restore from player id. Cells changed again since, by anyone, are left alone
+so a player's undo never wipes out another player's edit. What was put back goes
+on to, so undo and redo undo each other
+*/
+func (s *Server) revert(id int, from, to *[]change) {
+	if len(*from) == 0 {
+		return
+	}
+	c := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	current := make(map[board.Cell]board.CellContents)
+	for _, contents := range s.board.Contents() {
+		current[contents.Cell] = contents
+	}
+	var reverted change
+	for i := range c.after {
+		if current[c.after[i].Cell].Equal(c.after[i]) {
+			reverted.before = append(reverted.before, c.after[i])
+			reverted.after = append(reverted.after, c.before[i])
+		}
+	}
+	if len(reverted.after) == 0 {
+		return
+	}
+
+	e := board.Edit{Op: board.EditRestore, Contents: reverted.after}
+	s.board.ApplyEdit(e)
+	*to = append(*to, reverted)
+	s.broadcast(Message{Type: typeEdit, Player: id, Edit: &e}, 0)
+}
+
+// sends msg to every player but except, 0 sends to everyone
+func (s *Server) broadcast(msg Message, except int) {
+	for id := range s.players {
+		if id != except {
+			s.send(id, msg)
+		}
+	}
+}
+
+/*
+queues msg for player id, it is written by their write goroutine so a slow player
+never holds up the others. A player who already has a full queue is too far
+behind, their connection is closed, which ends their handle loop and removes them
+*/
+func (s *Server) send(id int, msg Message) {
+	p := s.players[id]
+	select {
+	case p.out <- msg:
+	default:
+		p.conn.Close()
+	}
+}
+
+// writes the player's queued messages in order until out is closed. If one can't
+// be sent the connection is closed, and the rest are dropped
+func (p *player) write() {
+	for msg := range p.out {
+		if err := p.conn.Send(msg); err != nil {
+			p.conn.Close()
+		}
+	}
+}
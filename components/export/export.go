@@ -0,0 +1,316 @@
+/*
+Package export lays puzzles out on printable A4 pages and writes them as SVG
+or PDF, for printing puzzles away from the terminal. Both writers are our own
+and only draw lines and text in the PDF standard fonts, so nothing is needed
+to print but this package.
+
+A page holds 1, 2, 4 or 6 puzzles with a caption each. Boxes, jigsaw regions
+and the edges of samurai grids get thick borders, killer cages are dashed with
+their sum in the corner, and candidate marks can be pencilled into every empty
+cell. The solutions follow the puzzles on pages of their own, with the givens
+in bold.
+*/
+package export
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// puzzles a page can hold
+var Layouts = []int{1, 2, 4, 6}
+
+// how puzzles are laid out
+type Options struct {
+	PerPage    int  // puzzles on a page, see Layouts
+	Candidates bool // pencil every candidate the givens leave into the empty cells
+	Solutions  bool // add pages with the solutions after the puzzles
+}
+
+// A4 in points, the unit of both PDF and our SVG viewBox
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 40.0
+	captionGap = 22.0 // room above each board for its caption
+	slotGap    = 18.0 // room between boards
+)
+
+// line widths in points, for a board on a page of its own
+const (
+	thinLine  = 0.6
+	thickLine = 2.2
+	cageLine  = 0.7
+)
+
+// a line to stroke, from x1, y1 to x2, y2 measured from the top left of the page
+type line struct {
+	x1, y1, x2, y2 float64
+	width          float64
+	dashed         bool
+}
+
+// text to draw with its baseline at y, starting at x or centered on it
+type text struct {
+	x, y     float64
+	size     float64
+	bold     bool
+	centered bool
+	s        string
+}
+
+// one page of lines and text, see Pages
+type Page struct {
+	lines []line
+	texts []text
+}
+
+// returns true if n is one of Layouts
+func IsLayout(n int) bool {
+	for _, layout := range Layouts {
+		if layout == n {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Lays puzzles out on pages, opts.PerPage to a page in the order given, followed
+by pages with their solutions if opts.Solutions is set. Thermometers, arrows
+and kropki dots can't be printed yet, puzzles with them are an error, and so
+are puzzles with no solution when solutions are asked for
+*/
+func Pages(puzzles []board.Puzzle, opts Options) ([]Page, error) {
+	if !IsLayout(opts.PerPage) {
+		return nil, fmt.Errorf("can't put %d puzzles on a page", opts.PerPage)
+	}
+
+	var solutions []string
+	for i, p := range puzzles {
+		if p.HasClues() {
+			return nil, fmt.Errorf("puzzle %d: thermometers, arrows and kropki dots can't be printed", i+1)
+		}
+		if opts.Solutions {
+			n, solution := p.Solve(1)
+			if n == 0 {
+				return nil, fmt.Errorf("puzzle %d has no solution", i+1)
+			}
+			solutions = append(solutions, solution)
+		}
+	}
+
+	var pages []Page
+	for start := 0; start < len(puzzles); start += opts.PerPage {
+		var page Page
+		for i := start; i < start+opts.PerPage && i < len(puzzles); i++ {
+			page.drawPuzzle(puzzles[i], i-start, opts, "", caption("Puzzle", i, puzzles[i]))
+		}
+		pages = append(pages, page)
+	}
+	for start := 0; start < len(solutions); start += opts.PerPage {
+		var page Page
+		for i := start; i < start+opts.PerPage && i < len(solutions); i++ {
+			page.drawPuzzle(puzzles[i], i-start, opts, solutions[i], fmt.Sprintf("Solution %d", i+1))
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// returns the caption of the puzzle at index i, its number, grade and any rules on top of
+// the classic ones, so the page says how to solve it
+func caption(label string, i int, p board.Puzzle) string {
+	parts := []string{fmt.Sprintf("%s %d - %s", label, i+1, p.Grade())}
+	if _, sums := p.Cages(); len(sums) > 0 {
+		parts = append(parts, "killer")
+	}
+	parts = append(parts, p.Variants()...)
+	return strings.Join(parts, ", ")
+}
+
+// returns the columns and rows of boards on a page of perPage
+func grid(perPage int) (int, int) {
+	switch perPage {
+	case 1:
+		return 1, 1
+	case 2:
+		return 1, 2
+	case 4:
+		return 2, 2
+	}
+	return 2, 3
+}
+
+/*
+draws p in the slot at index of the page. With a solution, written like
+board.Puzzle.Solution, its values fill the empty cells and the givens are in
+bold, otherwise candidates are pencilled in if opts asks for them
+*/
+func (page *Page) drawPuzzle(p board.Puzzle, index int, opts Options, solution string, title string) {
+	cols, rows := grid(opts.PerPage)
+	slotWidth := (pageWidth - 2*margin - float64(cols-1)*slotGap) / float64(cols)
+	slotHeight := (pageHeight - 2*margin - float64(rows-1)*slotGap) / float64(rows)
+	side := math.Min(slotWidth, slotHeight-captionGap)
+
+	regions := p.Regions()
+	n := len(regions)
+	cell := side / float64(n)
+	x0 := margin + float64(index%cols)*(slotWidth+slotGap) + (slotWidth-side)/2
+	y0 := margin + float64(index/cols)*(slotHeight+slotGap) + captionGap + (slotHeight-captionGap-side)/2
+	// lines thin out with the board, so a page of six looks like a page of one
+	scale := side / (pageWidth - 2*margin)
+
+	page.texts = append(page.texts, text{x: x0, y: y0 - captionGap/2, size: 11, s: title})
+
+	// every cell edge with the board on either side, thick between regions
+	regionAt := func(i, j int) int {
+		if i < 0 || i >= n || j < 0 || j >= n {
+			return -1
+		}
+		return regions[i][j]
+	}
+	edge := func(a, b int) (bool, float64) {
+		if a == -1 && b == -1 {
+			return false, 0
+		}
+		if a != b {
+			return true, thickLine * scale
+		}
+		return true, thinLine * scale
+	}
+	// thick lines go on top, so the thin ones don't cut into their corners
+	var thin, thick []line
+	add := func(l line) {
+		if l.width > thinLine*scale {
+			thick = append(thick, l)
+		} else {
+			thin = append(thin, l)
+		}
+	}
+	for i := 0; i <= n; i++ {
+		for j := 0; j < n; j++ {
+			y, x := y0+float64(i)*cell, x0+float64(j)*cell
+			if ok, w := edge(regionAt(i-1, j), regionAt(i, j)); ok {
+				add(line{x1: x, y1: y, x2: x + cell, y2: y, width: w})
+			}
+			y, x = y0+float64(j)*cell, x0+float64(i)*cell
+			if ok, w := edge(regionAt(j, i-1), regionAt(j, i)); ok {
+				add(line{x1: x, y1: y, x2: x, y2: y + cell, width: w})
+			}
+		}
+	}
+	page.lines = append(page.lines, thin...)
+	page.lines = append(page.lines, thick...)
+
+	page.drawCages(p, x0, y0, cell, scale)
+
+	givens := p.Line()
+	candidates := p.Candidates()
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			cx, cy := x0+(float64(j)+0.5)*cell, y0+(float64(i)+0.5)*cell
+			given := givens[i*n+j]
+			switch {
+			case given != '.':
+				page.drawValue(cx, cy, cell, solution != "", string(given))
+			case solution != "" && regions[i][j] != -1:
+				page.drawValue(cx, cy, cell, false, string(solution[i*n+j]))
+			case opts.Candidates && solution == "":
+				page.drawCandidates(cx, cy, cell, candidates[i][j], p.Size())
+			}
+		}
+	}
+}
+
+// draws a value centered on cx, cy
+func (page *Page) drawValue(cx, cy, cell float64, bold bool, s string) {
+	size := cell * 0.6
+	page.texts = append(page.texts, text{x: cx, y: cy + size*0.36, size: size, bold: bold, centered: true, s: s})
+}
+
+// pencils candidates into the cell centered on cx, cy, in a square of rows from 1 up
+// to values
+func (page *Page) drawCandidates(cx, cy, cell float64, candidates sudoku.Candidates, values int) {
+	if candidates == 0 {
+		return
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(values))))
+	rows := (values + cols - 1) / cols
+	step := cell * 0.8 / float64(cols)
+	size := step * 0.75
+	candidates.Iterate(func(val int) {
+		row, col := (val-1)/cols, (val-1)%cols
+		x := cx + (float64(col)-float64(cols-1)/2)*step
+		y := cy + (float64(row)-float64(rows-1)/2)*step
+		page.texts = append(page.texts, text{x: x, y: y + size*0.36, size: size, centered: true, s: sudoku.FormatValue(val)})
+	})
+}
+
+/*
+draws killer cages as dashed outlines just inside the cells, with the sum in the
+top left corner of each cage's first cell. Each side of a cell facing another
+cage gets a line, stretched to the cell's edge where the cage carries on past it
+*/
+func (page *Page) drawCages(p board.Puzzle, x0, y0, cell, scale float64) {
+	cageOf, sums := p.Cages()
+	if len(sums) == 0 {
+		return
+	}
+	n := len(cageOf)
+	inset := cell * 0.08
+	in := func(i, j, idx int) bool {
+		return i >= 0 && i < n && j >= 0 && j < n && cageOf[i][j] == idx
+	}
+	dash := func(x1, y1, x2, y2 float64) {
+		page.lines = append(page.lines, line{x1: x1, y1: y1, x2: x2, y2: y2, width: cageLine * scale, dashed: true})
+	}
+
+	labelled := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			idx := cageOf[i][j]
+			if idx == -1 {
+				continue
+			}
+			left, top := x0+float64(j)*cell, y0+float64(i)*cell
+			right, bottom := left+cell, top+cell
+			// where the side lines start and end, the cell's edge if the cage goes on that way
+			x1, x2, y1, y2 := left+inset, right-inset, top+inset, bottom-inset
+			if in(i, j-1, idx) {
+				x1 = left
+			}
+			if in(i, j+1, idx) {
+				x2 = right
+			}
+			if in(i-1, j, idx) {
+				y1 = top
+			}
+			if in(i+1, j, idx) {
+				y2 = bottom
+			}
+			if !in(i-1, j, idx) {
+				dash(x1, top+inset, x2, top+inset)
+			}
+			if !in(i+1, j, idx) {
+				dash(x1, bottom-inset, x2, bottom-inset)
+			}
+			if !in(i, j-1, idx) {
+				dash(left+inset, y1, left+inset, y2)
+			}
+			if !in(i, j+1, idx) {
+				dash(right-inset, y1, right-inset, y2)
+			}
+
+			if !labelled[idx] {
+				labelled[idx] = true
+				size := cell * 0.22
+				page.texts = append(page.texts, text{x: left + inset*1.5, y: top + inset*1.5 + size*0.72, size: size, s: fmt.Sprint(sums[idx])})
+			}
+		}
+	}
+}
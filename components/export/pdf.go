@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// widths of the characters we center in Helvetica, in thousandths of the font size.
+// Digits are all the same width, other characters are close enough to it
+var helveticaWidths = map[rune]float64{
+	'A': 667, 'B': 667, 'C': 722, 'D': 722, 'E': 667, 'F': 611, 'G': 778,
+}
+
+const digitWidth = 556
+
+// returns the width of s drawn in Helvetica at size
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = digitWidth
+		}
+		width += w
+	}
+	return width * size / 1000
+}
+
+/*
+Writes pages to w as a PDF document of A4 pages. Text is set in Helvetica, one
+of the fonts every PDF reader has, so nothing is embedded. The layout is the
+smallest a reader accepts: the catalog, the page tree and two fonts, then a
+page and its content stream for every page, and the cross reference table
+*/
+func WritePDF(w io.Writer, pages []Page) error {
+	var doc bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// objects 1 to 4, pages start at 5 with their content right after them
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	doc.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), 6+2*i))
+		content := page.pdfContent()
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
+
+// returns the content stream drawing page. PDF measures from the bottom left, we
+// measure from the top left, so every y is flipped
+func (page Page) pdfContent() string {
+	var b strings.Builder
+	b.WriteString("2 J\n") // square line caps, like the SVG
+	for _, l := range page.lines {
+		dash := "[] 0 d"
+		if l.dashed {
+			dash = fmt.Sprintf("[%s %s] 0 d", num(l.width*3), num(l.width*3))
+		}
+		fmt.Fprintf(&b, "%s w %s %s %s m %s %s l S\n",
+			num(l.width), dash, num(l.x1), num(pageHeight-l.y1), num(l.x2), num(pageHeight-l.y2))
+	}
+	for _, t := range page.texts {
+		font, x := "F1", t.x
+		if t.bold {
+			font = "F2"
+		}
+		if t.centered {
+			x -= textWidth(t.s, t.size) / 2
+		}
+		fmt.Fprintf(&b, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(t.size), num(x), num(pageHeight-t.y), pdfString(t.s))
+	}
+	return b.String()
+}
+
+// escapes s for a PDF string, the characters we draw are all ASCII
+func pdfString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
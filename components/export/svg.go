@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writes page to w as an SVG image the size of an A4 page
+func WriteSVG(w io.Writer, page Page) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="210mm" height="297mm" viewBox="0 0 %s %s">`+"\n",
+		num(pageWidth), num(pageHeight))
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	for _, l := range page.lines {
+		dash := ""
+		if l.dashed {
+			dash = fmt.Sprintf(` stroke-dasharray="%s %s"`, num(l.width*3), num(l.width*3))
+		}
+		fmt.Fprintf(out, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="black" stroke-width="%s" stroke-linecap="square"%s/>`+"\n",
+			num(l.x1), num(l.y1), num(l.x2), num(l.y2), num(l.width), dash)
+	}
+	for _, t := range page.texts {
+		attrs := ""
+		if t.bold {
+			attrs += ` font-weight="bold"`
+		}
+		if t.centered {
+			attrs += ` text-anchor="middle"`
+		}
+		var s strings.Builder
+		xml.EscapeText(&s, []byte(t.s))
+		fmt.Fprintf(out, `<text x="%s" y="%s" font-family="Helvetica, Arial, sans-serif" font-size="%s"%s>%s</text>`+"\n",
+			num(t.x), num(t.y), num(t.size), attrs, s.String())
+	}
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+// writes a length in points, to a hundredth
+func num(f float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", f), "0")
+	return strings.TrimSuffix(s, ".")
+}
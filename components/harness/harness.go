@@ -0,0 +1,298 @@
+/*
+Package harness plays the game without a terminal, for tests. A Harness holds
+the app model, feeds it key presses and window sizes the way Bubble Tea would,
+runs the commands it returns and feeds their messages back in, so a test can
+script a game and check the board and the view after every step:
+
+	h := harness.NewPuzzle(t, "21..342...3...1.", settings.Settings{})
+	h.Resize(80, 30)
+	h.Keys("right", "right", "4", "ctrl+z")
+	h.AssertValue(board.Cell{Row: 0, Col: 2}, -1)
+	h.Golden("undo")
+
+Golden views are kept in testdata/<name>.golden next to the test, run the test
+with -update to write them. Views are drawn without color and kept without
+escape codes, so they read the same on every machine and in a diff.
+*/
+package harness
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/Alex-Merrill/sudoku-tui/components"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var update = flag.Bool("update", false, "write golden views instead of comparing against them")
+
+// how long a command gets to return its message, see Harness.Timeout
+const defaultTimeout = 50 * time.Millisecond
+
+// messages fed back into the model from one Send, more means commands keep asking
+// for each other and the game would never settle
+const maxMessages = 1000
+
+// plays one game, see the package doc
+type Harness struct {
+	t     testing.TB
+	model model.Model
+	msgs  []tea.Msg
+
+	// commands that take longer than this to return fail the test, as the game would sit
+	// waiting on them. The win screen's animation timers are the exception, once the game
+	// is won slow commands are dropped
+	Timeout time.Duration
+}
+
+// starts a game of opts, drawn without color unless s has a renderer of its own
+func New(t testing.TB, opts board.GameOptions, s settings.Settings) *Harness {
+	t.Helper()
+	if s.Renderer == nil {
+		s.Renderer = lipgloss.NewRenderer(io.Discard)
+		s.Renderer.SetColorProfile(termenv.Ascii)
+	}
+	m, err := model.NewModel(opts, s)
+	if err != nil {
+		t.Fatalf("starting game: %v", err)
+	}
+	h := &Harness{t: t, model: m, Timeout: defaultTimeout}
+	h.run(h.model.Init(), 0)
+	return h
+}
+
+// starts a game of the puzzle written on one line, see board.ParsePuzzleLine
+func NewPuzzle(t testing.TB, line string, s settings.Settings) *Harness {
+	t.Helper()
+	p, err := board.ParsePuzzleLine(line)
+	if err == nil {
+		// lines only have the givens, reading the puzzle back as a file solves it for the answer key
+		p, err = board.ParsePuzzle(strings.NewReader(p.Text()))
+	}
+	if err != nil {
+		t.Fatalf("puzzle %q: %v", line, err)
+	}
+	return New(t, board.GameOptions{Size: p.Size(), Puzzle: &p}, s)
+}
+
+// names of the keys that aren't a single character, like "up" and "ctrl+z"
+var keyTypes = func() map[string]tea.KeyType {
+	types := make(map[string]tea.KeyType)
+	for k := tea.KeyType(-128); k < 128; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			types[name] = k
+		}
+	}
+	return types
+}()
+
+// returns the key press s names, as KeyMsg.String writes it: "a", "!", "up", "shift+left",
+// "ctrl+z", " " for space, and "alt+" in front of any of them
+func Key(s string) tea.KeyMsg {
+	var k tea.Key
+	if rest := strings.TrimPrefix(s, "alt+"); rest != s && rest != "" {
+		k.Alt, s = true, rest
+	}
+	if t, ok := keyTypes[s]; ok {
+		k.Type = t
+	} else {
+		k.Type, k.Runes = tea.KeyRunes, []rune(s)
+	}
+	return tea.KeyMsg(k)
+}
+
+// presses keys in order, see Key
+func (h *Harness) Keys(keys ...string) {
+	h.t.Helper()
+	for _, k := range keys {
+		h.Send(Key(k))
+	}
+}
+
+// resizes the window the game is drawn in
+func (h *Harness) Resize(width, height int) {
+	h.Send(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// updates the model with each of msgs in turn, then with the messages their commands return
+func (h *Harness) Send(msgs ...tea.Msg) {
+	h.t.Helper()
+	for _, msg := range msgs {
+		h.update(msg, 0)
+	}
+}
+
+func (h *Harness) update(msg tea.Msg, fed int) int {
+	h.t.Helper()
+	next, cmd := h.model.Update(msg)
+	h.model = next.(model.Model)
+	return h.run(cmd, fed)
+}
+
+/*
+runs cmd and feeds its message back into the model, returning how many messages
+have been fed back so far. Batches are taken apart like Bubble Tea does, by
+looking inside them since their type is Bubble Tea's own
+*/
+func (h *Harness) run(cmd tea.Cmd, fed int) int {
+	h.t.Helper()
+	if cmd == nil {
+		return fed
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(h.Timeout):
+		if !h.Won() {
+			h.t.Errorf("a command took longer than %s to return its message", h.Timeout)
+		}
+		return fed
+	}
+	if msg == nil {
+		return fed
+	}
+
+	if batch := reflect.ValueOf(msg); batch.Kind() == reflect.Slice && batch.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+		for i := 0; i < batch.Len(); i++ {
+			fed = h.run(batch.Index(i).Interface().(tea.Cmd), fed)
+		}
+		return fed
+	}
+
+	if fed++; fed > maxMessages {
+		h.t.Fatalf("commands returned more than %d messages, last %T", maxMessages, msg)
+	}
+	h.msgs = append(h.msgs, msg)
+	return h.update(msg, fed)
+}
+
+// returns the app model as it is now
+func (h *Harness) Model() model.Model {
+	return h.model
+}
+
+// returns the state of the board as it is now
+func (h *Harness) Board() board.Snapshot {
+	return h.model.Board().Snapshot()
+}
+
+// returns the cursor cell and the selected cells in reading order
+func (h *Harness) Cursor() (board.Cell, []board.Cell) {
+	return h.model.Board().Cursor()
+}
+
+// returns every message commands have returned so far, in order
+func (h *Harness) Messages() []tea.Msg {
+	return h.msgs
+}
+
+// returns true once the game was won and the win screen shows
+func (h *Harness) Won() bool {
+	_, _, over := h.model.Game()
+	return over
+}
+
+// returns the view as it is now
+func (h *Harness) View() string {
+	return h.model.View()
+}
+
+// fails the test unless cell holds val, -1 for empty
+func (h *Harness) AssertValue(cell board.Cell, val int8) {
+	h.t.Helper()
+	if got := h.cell(cell).Val; got != val {
+		h.t.Errorf("%s holds %d, want %d", cell, got, val)
+	}
+}
+
+// fails the test unless cell has exactly the pencil marks pencils
+func (h *Harness) AssertPencils(cell board.Cell, pencils ...int8) {
+	h.t.Helper()
+	got := h.cell(cell).Pencils
+	if len(got) != len(pencils) || (len(got) > 0 && !reflect.DeepEqual(got, pencils)) {
+		h.t.Errorf("%s has pencil marks %v, want %v", cell, got, pencils)
+	}
+}
+
+// fails the test unless exactly cells are wrong
+func (h *Harness) AssertWrong(cells ...board.Cell) {
+	h.t.Helper()
+	got := h.Board().Wrong
+	if len(got) != len(cells) || (len(got) > 0 && !reflect.DeepEqual(got, cells)) {
+		h.t.Errorf("wrong cells %v, want %v", got, cells)
+	}
+}
+
+// fails the test unless the board has n cells left to fill or fix
+func (h *Harness) AssertCellsLeft(n int) {
+	h.t.Helper()
+	if got := h.Board().CellsLeft; got != n {
+		h.t.Errorf("%d cells left, want %d", got, n)
+	}
+}
+
+// fails the test unless the board is at board state undo of an undo history of history states
+func (h *Harness) AssertUndo(undo, history int) {
+	h.t.Helper()
+	if s := h.Board(); s.Undo != undo || s.History != history {
+		h.t.Errorf("at board state %d of %d, want %d of %d", s.Undo, s.History, undo, history)
+	}
+}
+
+// fails the test unless the cursor is on cell
+func (h *Harness) AssertCursor(cell board.Cell) {
+	h.t.Helper()
+	if got, _ := h.Cursor(); got != cell {
+		h.t.Errorf("cursor on %s, want %s", got, cell)
+	}
+}
+
+// escape codes a view may hold even without color, like resets
+var escapeCodes = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// fails the test unless the view without escape codes matches testdata/<name>.golden,
+// or writes it there when the test runs with -update
+func (h *Harness) Golden(name string) {
+	h.t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	view := escapeCodes.ReplaceAllString(h.View(), "")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			h.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(view), 0o644); err != nil {
+			h.t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("%v, run the test with -update to write it", err)
+	}
+	if view != string(want) {
+		h.t.Errorf("view doesn't match %s, run the test with -update and diff it to see how\ngot:\n%s\nwant:\n%s", path, view, want)
+	}
+}
+
+func (h *Harness) cell(cell board.Cell) board.CellContents {
+	h.t.Helper()
+	cells := h.Board().Cells
+	if cell.Row < 0 || cell.Row >= len(cells) || cell.Col < 0 || cell.Col >= len(cells[cell.Row]) {
+		h.t.Fatalf("%s is off the board", cell)
+	}
+	return cells[cell.Row][cell.Col]
+}
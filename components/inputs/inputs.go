@@ -3,22 +3,26 @@ package inputs
 import "github.com/charmbracelet/bubbles/key"
 
 type KeyMap struct {
-	Up           key.Binding
-	Down         key.Binding
-	Left         key.Binding
-	Right        key.Binding
-	ShiftUp      key.Binding
-	ShiftDown    key.Binding
-	ShiftLeft    key.Binding
-	ShiftRight   key.Binding
-	Number       key.Binding
-	PencilNumber key.Binding
-	Delete       key.Binding
-	Undo         key.Binding
-	Redo         key.Binding
-	Quit         key.Binding
-	Help         key.Binding
-	NewGame      key.Binding
+	Up              key.Binding
+	Down            key.Binding
+	Left            key.Binding
+	Right           key.Binding
+	ShiftUp         key.Binding
+	ShiftDown       key.Binding
+	ShiftLeft       key.Binding
+	ShiftRight      key.Binding
+	Number          key.Binding
+	PencilNumber    key.Binding
+	Delete          key.Binding
+	Undo            key.Binding
+	Redo            key.Binding
+	Quit            key.Binding
+	Help            key.Binding
+	NewGame         key.Binding
+	Colorblind      key.Binding
+	Layout          key.Binding
+	HighlightPeers  key.Binding
+	HighlightDigits key.Binding
 }
 
 func (k KeyMap) ShortHelp() []key.Binding {
@@ -30,6 +34,7 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 		{k.Up, k.Down, k.Left, k.Right, k.ShiftUp, k.ShiftDown, k.ShiftLeft, k.ShiftRight}, // first column
 		{k.Number, k.PencilNumber, k.Delete, k.Undo, k.Redo},                               // third column
 		{k.Help, k.Quit, k.NewGame},                                                        // fifth column
+		{k.Colorblind, k.Layout, k.HighlightPeers, k.HighlightDigits},                      // seventh column
 	}
 }
 
@@ -67,12 +72,12 @@ var Controls = KeyMap{
 		key.WithHelp("shift+→/shift+l", "highlight right"),
 	),
 	Number: key.NewBinding(
-		key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"),
-		key.WithHelp("1-9", "input number"),
+		key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e", "f", "g"),
+		key.WithHelp("1-9/a-g", "input number, a-g for 10-16"),
 	),
 	PencilNumber: key.NewBinding(
-		key.WithKeys("!", "@", "#", "$", "%", "^", "&", "*", "("),
-		key.WithHelp("shift+[1-9]", "pencil mark/unmark number"),
+		key.WithKeys("!", "@", "#", "$", "%", "^", "&", "*", "(", "A", "B", "C", "D", "E", "F", "G"),
+		key.WithHelp("shift+[1-9/a-g]", "pencil mark/unmark number"),
 	),
 	Delete: key.NewBinding(
 		key.WithKeys("backspace"),
@@ -98,4 +103,90 @@ var Controls = KeyMap{
 		key.WithKeys("n"),
 		key.WithHelp("n", "new game"),
 	),
+	Colorblind: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "toggle colorblind mode"),
+	),
+	Layout: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "cycle board layout"),
+	),
+	HighlightPeers: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "toggle row/col/box highlight"),
+	),
+	HighlightDigits: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "toggle matching digit highlight"),
+	),
+}
+
+// keys of the replay viewer, which shares Quit, Help and the display toggles with the game
+type ReplayKeyMap struct {
+	PlayPause   key.Binding
+	Faster      key.Binding
+	Slower      key.Binding
+	StepForward key.Binding
+	StepBack    key.Binding
+	SeekForward key.Binding
+	SeekBack    key.Binding
+	SeekPercent key.Binding
+	Start       key.Binding
+	End         key.Binding
+}
+
+func (k ReplayKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.PlayPause, Controls.Help, Controls.Quit}
+}
+
+func (k ReplayKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.PlayPause, k.Faster, k.Slower},                                    // first column
+		{k.StepForward, k.StepBack, k.SeekForward, k.SeekBack},               // third column
+		{k.SeekPercent, k.Start, k.End},                                      // fifth column
+		{Controls.Help, Controls.Quit, Controls.Colorblind, Controls.Layout}, // seventh column
+	}
+}
+
+var ReplayControls = ReplayKeyMap{
+	PlayPause: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "play/pause"),
+	),
+	Faster: key.NewBinding(
+		key.WithKeys("+", "="),
+		key.WithHelp("+", "play faster"),
+	),
+	Slower: key.NewBinding(
+		key.WithKeys("-"),
+		key.WithHelp("-", "play slower"),
+	),
+	StepForward: key.NewBinding(
+		key.WithKeys("l", "right"),
+		key.WithHelp("→/l", "step forward"),
+	),
+	StepBack: key.NewBinding(
+		key.WithKeys("h", "left"),
+		key.WithHelp("←/h", "step back"),
+	),
+	SeekForward: key.NewBinding(
+		key.WithKeys("L", "shift+right"),
+		key.WithHelp("shift+→/shift+l", "skip 10s forward"),
+	),
+	SeekBack: key.NewBinding(
+		key.WithKeys("H", "shift+left"),
+		key.WithHelp("shift+←/shift+h", "skip 10s back"),
+	),
+	SeekPercent: key.NewBinding(
+		key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		key.WithHelp("0-9", "jump to 0%-90%"),
+	),
+	Start: key.NewBinding(
+		key.WithKeys("g", "home"),
+		key.WithHelp("g/home", "jump to start"),
+	),
+	End: key.NewBinding(
+		key.WithKeys("G", "end"),
+		key.WithHelp("G/end", "jump to end"),
+	),
 }
@@ -2,10 +2,12 @@ package menu
 
 import (
 	"github.com/Alex-Merrill/sudoku-tui/components/inputs"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type Model struct {
@@ -13,9 +15,23 @@ type Model struct {
 	keys inputs.KeyMap
 }
 
-func NewModel() Model {
+func NewModel(s settings.Settings) Model {
+	h := help.New()
+	// help's styles draw on our own terminal, so they are drawn again on the settings' one
+	restyle := func(style lipgloss.Style) lipgloss.Style {
+		return s.NewStyle().Inherit(style)
+	}
+	h.Styles = help.Styles{
+		Ellipsis:       restyle(h.Styles.Ellipsis),
+		ShortKey:       restyle(h.Styles.ShortKey),
+		ShortDesc:      restyle(h.Styles.ShortDesc),
+		ShortSeparator: restyle(h.Styles.ShortSeparator),
+		FullKey:        restyle(h.Styles.FullKey),
+		FullDesc:       restyle(h.Styles.FullDesc),
+		FullSeparator:  restyle(h.Styles.FullSeparator),
+	}
 	return Model{
-		help: help.New(),
+		help: h,
 		keys: inputs.Controls,
 	}
 }
@@ -1,11 +1,18 @@
 package model
 
 import (
-	//"fmt"
+	"fmt"
+	"time"
 
 	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/coop"
 	"github.com/Alex-Merrill/sudoku-tui/components/inputs"
 	"github.com/Alex-Merrill/sudoku-tui/components/menu"
+	"github.com/Alex-Merrill/sudoku-tui/components/numpad"
+	"github.com/Alex-Merrill/sudoku-tui/components/race"
+	"github.com/Alex-Merrill/sudoku-tui/components/replay"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	"github.com/Alex-Merrill/sudoku-tui/components/spectate"
 	"github.com/Alex-Merrill/sudoku-tui/components/winscreen"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -13,21 +20,38 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// blank lines between the board and the menu, and spaces between the board and the side panels
+const (
+	menuSeparator   = "\n\n"
+	numpadSeparator = "   "
+)
+
 // Defines App Model
 // Consists of board component and menu component
 type Model struct {
-	mode          int
+	options       board.GameOptions
+	settings      settings.Settings
 	board         board.Model
 	menu          menu.Model
+	numpad        numpad.Model
 	winscreen     winscreen.Model
+	race          race.Model       // opponent's progress in a race, does nothing outside of races
+	coop          coop.Model       // players of a shared board, does nothing outside of coop games
+	watch         spectate.Model   // the game we mirror as a spectator, does nothing unless watching
+	spectators    *spectate.Server // serves our board to spectators, nil without spectators
+	recorder      *replay.Recorder // writes our games to a replay file, nil when not recording
 	gameWon       bool
+	gameLost      bool          // the opponent won the race
+	err           error         // why the last puzzle couldn't be generated, shown instead of the board
+	started       time.Time     // when the game started, for its clock
+	elapsed       time.Duration // how long the game took, once it is won
 	winscreenDone bool
 
 	width, height int
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(m.race.Init(), m.coop.Init(), m.watch.Init())
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -40,9 +64,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, inputs.Controls.Quit):
 			return m, tea.Quit
 
-		case key.Matches(msg, inputs.Controls.NewGame):
-			m.board = board.NewModel(m.mode)
+		// races and coop games are one puzzle, every player would have to start the next one together,
+		// and spectators only watch the player's games
+		case key.Matches(msg, inputs.Controls.NewGame) && !m.race.Racing() && !m.coop.Active() && !m.watch.Watching():
+			// seeded games get a new seed, so the next puzzle is new and can still be saved
+			if m.options.Seed != 0 {
+				m.options.Seed = time.Now().UnixNano()
+			}
+			b, err := board.NewModel(m.options, m.settings)
+			m.err = err
+			if err != nil {
+				break
+			}
+			m.board = b
 			m.gameWon = false
+			m.started = time.Now()
+			if m.recorder != nil {
+				m.board.SetRecording(true)
+				m.recorder.Game(m.options)
+			}
+
+		case key.Matches(msg, inputs.Controls.Colorblind):
+			m.settings.Colorblind = !m.settings.Colorblind
+			m.board.SetSettings(m.settings)
+			m.numpad.SetSettings(m.settings)
+			m.race.SetSettings(m.settings)
+			m.coop.SetSettings(m.settings)
+			m.watch.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.HighlightPeers):
+			m.settings.HighlightPeers = !m.settings.HighlightPeers
+			m.board.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.HighlightDigits):
+			m.settings.HighlightDigits = !m.settings.HighlightDigits
+			m.board.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.Layout):
+			m.settings.Layout = m.settings.Layout.Next()
+			m.board.SetSettings(m.settings)
 
 		}
 
@@ -51,42 +111,280 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case board.GameWon:
+		// in a race we only won if we finished first, the race tells us when it knows
+		if m.race.Racing() {
+			initCmd = m.race.Finish(m.board.Contents())
+			break
+		}
+		m.gameWon = true
+		m.elapsed = time.Since(m.started)
+		m.winscreen = winscreen.NewModel(m.width, m.height, m.settings)
+		initCmd = m.winscreen.Init()
+
+	// a shared board sends its edits through the server, which sends them back to every player
+	case board.EditMsg:
+		m.coop.SendEdit(msg.Edit)
+
+	case coop.EditReceived:
+		m.board.ApplyEdit(msg.Edit)
+
+	case board.Edited:
+		if m.recorder != nil {
+			m.recorder.Edit(msg.Edit)
+		}
+
+	// a spectator's board follows the player's
+	case spectate.GameStarted:
+		b, err := board.NewModel(msg.Options, m.settings)
+		m.err = err
+		if err != nil {
+			break
+		}
+		m.options = msg.Options
+		m.board = b
+		m.gameWon = false
+
+	// without the player's puzzle there is no board to follow
+	case spectate.BoardUpdated:
+		if m.err != nil {
+			break
+		}
+		m.board.Resume(msg.Contents)
+		m.board.SetCursor(msg.Cursor, msg.Selected)
+		if msg.Won && !m.gameWon {
+			m.gameWon = true
+			m.winscreen = winscreen.NewModel(m.width, m.height, m.settings)
+			initCmd = m.winscreen.Init()
+		}
+
+	case race.Finished:
+		if !msg.Won {
+			m.gameLost = true
+			break
+		}
 		m.gameWon = true
-		m.winscreen = winscreen.NewModel(m.width, m.height)
+		m.elapsed = time.Since(m.started)
+		m.winscreen = winscreen.NewModel(m.width, m.height, m.settings)
 		initCmd = m.winscreen.Init()
 
 	}
 
-	var boardCmd, winScreenCmd tea.Cmd
+	var boardCmd, winScreenCmd, raceCmd, coopCmd, watchCmd tea.Cmd
 
-	// update board, menu, and winscreen models
-	m.board, boardCmd = m.board.Update(msg)
+	// update board, menu, and winscreen models, a spectator's keys don't reach the board
+	cursor, selected := m.board.Cursor()
+	if _, isKey := msg.(tea.KeyMsg); !isKey || !m.watch.Watching() {
+		m.board, boardCmd = m.board.Update(msg)
+	}
+	if m.recorder != nil {
+		if newCursor, newSelected := m.board.Cursor(); newCursor != cursor || !sameCells(newSelected, selected) {
+			m.recorder.Cursor(newCursor, newSelected)
+		}
+	}
 	m.menu, _ = m.menu.Update(msg)
+	m.numpad, _ = m.numpad.Update(msg)
 	m.winscreen, winScreenCmd = m.winscreen.Update(msg)
+	m.race, raceCmd = m.race.Update(msg)
+	m.coop, coopCmd = m.coop.Update(msg)
+	m.watch, watchCmd = m.watch.Update(msg)
+
+	// keep the numpad counts and the opponent in sync with the board
+	m.numpad.SetDigitsLeft(m.board.DigitsLeft())
+	filled, total := m.board.Progress()
+	m.race.SetProgress(filled, total, m.board.Mistakes())
+
+	// and the other players of a coop game, without a server the board is ours alone
+	if m.coop.Active() {
+		m.board.SetShared(m.coop.Connected())
+		m.coop.SetCursor(m.board.Cursor())
+		m.board.SetPlayers(m.coop.Players())
+	}
+
+	if m.spectators != nil {
+		cursor, selected := m.board.Cursor()
+		m.spectators.Publish(m.options, m.board.Contents(), cursor, selected, m.Elapsed(), m.gameWon)
+	}
 
-	return m, tea.Batch(boardCmd, winScreenCmd, initCmd)
+	// the board gets whatever room the menu and side panels leave it, the menu grows when full help is shown
+	panelWidth, menuHeight := m.chrome()
+	m.board.SetSize(m.width-panelWidth, m.height-menuHeight)
+
+	return m, tea.Batch(boardCmd, winScreenCmd, raceCmd, coopCmd, watchCmd, initCmd)
+}
+
+// returns the numpad, and the opponent's progress in a race, the players of a coop game,
+// or what a spectator is watching, as drawn next to the board
+func (m Model) sidePanels() string {
+	switch {
+	case m.race.Racing():
+		return lipgloss.JoinHorizontal(lipgloss.Center, numpadSeparator, m.numpad.View(), numpadSeparator, m.race.View())
+	case m.coop.Active():
+		return lipgloss.JoinHorizontal(lipgloss.Center, numpadSeparator, m.numpad.View(), numpadSeparator, m.coop.View())
+	case m.watch.Watching():
+		return lipgloss.JoinHorizontal(lipgloss.Center, numpadSeparator, m.numpad.View(), numpadSeparator, m.watch.View())
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Center, numpadSeparator, m.numpad.View())
+}
+
+// returns the width of the side panels and the height of the menu, the room they take from the board
+func (m Model) chrome() (int, int) {
+	return lipgloss.Width(m.sidePanels()), lipgloss.Height(menuSeparator + m.menu.View())
 }
 
 func (m Model) View() string {
 	if m.gameWon {
+		lines := []string{m.winscreen.View(), "Press 'n' to start a new game", "Press 'q' or 'ctrl+c' to quit"}
+		if m.race.Racing() {
+			lines = []string{m.winscreen.View(), "You finished first!", "Press 'q' or 'ctrl+c' to quit"}
+		}
+		if m.coop.Active() {
+			lines = []string{m.winscreen.View(), "You solved the puzzle together!", "Press 'q' or 'ctrl+c' to quit"}
+		}
+		if m.watch.Watching() {
+			lines = []string{m.winscreen.View(), "The player solved the puzzle!", "Press 'q' or 'ctrl+c' to quit"}
+		}
+		compositeView := lipgloss.JoinVertical(lipgloss.Center, lines...)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
+	}
+
+	if m.gameLost {
 		compositeView := lipgloss.JoinVertical(lipgloss.Center,
-			m.winscreen.View(),
-			"Press 'n' to start a new game",
+			"Your opponent finished first!",
 			"Press 'q' or 'ctrl+c' to quit")
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
 	}
 
-	compositeView := m.board.View() + "\n\n" + m.menu.View()
+	if m.err != nil {
+		help := "Press 'n' to try another puzzle or 'q' to quit"
+		if m.watch.Watching() {
+			help = "Waiting for the player's next game, press 'q' to quit"
+		}
+		compositeView := lipgloss.JoinVertical(lipgloss.Center, "Could not start the game", m.err.Error(), help)
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
+	}
+
+	if m.board.TooSmall() {
+		// the window has to fit the side panels and menu as well as the board
+		w, h := m.board.Size()
+		panelWidth, menuHeight := m.chrome()
+		compositeView := lipgloss.JoinVertical(lipgloss.Center,
+			"Terminal too small",
+			fmt.Sprintf("The %s layout needs %dx%d, have %dx%d", m.board.Layout(), w+panelWidth, h+menuHeight, m.width, m.height),
+			"Press 'v' to change layout or 'q' to quit")
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
+	}
+
+	boardView := lipgloss.JoinHorizontal(lipgloss.Center, m.board.View(), m.sidePanels())
+	compositeView := boardView + menuSeparator + m.menu.View()
 
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
 }
 
-func NewModel(mode int) Model {
+// Initializes the app model, or returns board.ErrGenerate if the puzzle of opts can't be generated
+func NewModel(opts board.GameOptions, s settings.Settings) (Model, error) {
+	b, err := board.NewModel(opts, s)
+	if err != nil {
+		return Model{}, err
+	}
+	n := numpad.NewModel(s)
+	n.SetDigitsLeft(b.DigitsLeft())
+
 	return Model{
-		mode:          mode,
-		board:         board.NewModel(mode),
-		menu:          menu.NewModel(),
+		options:       opts,
+		settings:      s,
+		board:         b,
+		menu:          menu.NewModel(s),
+		numpad:        n,
 		gameWon:       false,
 		winscreenDone: false,
+		started:       time.Now(),
+	}, nil
+}
+
+// Initializes the app model for a saved game, contents are its cells as returned by Game
+func NewSavedModel(opts board.GameOptions, s settings.Settings, contents []board.CellContents) (Model, error) {
+	m, err := NewModel(opts, s)
+	if err != nil {
+		return Model{}, err
+	}
+	m.board.Resume(contents)
+	m.numpad.SetDigitsLeft(m.board.DigitsLeft())
+	return m, nil
+}
+
+// returns the options of the game being played and the contents of its cells,
+// over is true once the game is won and there is nothing left to save
+func (m Model) Game() (opts board.GameOptions, contents []board.CellContents, over bool) {
+	return m.options, m.board.Contents(), m.gameWon
+}
+
+// Initializes the app model for a race against the player on the other end of conn,
+// opts are the options both players generated the puzzle from
+func NewRaceModel(opts board.GameOptions, s settings.Settings, conn *race.Conn, host bool) (Model, error) {
+	m, err := NewModel(opts, s)
+	if err != nil {
+		return Model{}, err
 	}
+	m.race = race.NewModel(conn, host, s, m.board)
+	return m, nil
+}
+
+// Initializes the app model for a coop game as player on the server at the other end
+// of conn, opts are the options every player generated the puzzle from
+func NewCoopModel(opts board.GameOptions, s settings.Settings, conn *coop.Conn, player int) (Model, error) {
+	m, err := NewModel(opts, s)
+	if err != nil {
+		return Model{}, err
+	}
+	m.coop = coop.NewModel(conn, player, s)
+	m.board.SetShared(true)
+	return m, nil
+}
+
+// Initializes the app model for a spectator watching the player at the other end of conn,
+// opts are the options of the game the player is playing
+func NewSpectatorModel(opts board.GameOptions, s settings.Settings, conn *spectate.Conn) (Model, error) {
+	m, err := NewModel(opts, s)
+	if err != nil {
+		return Model{}, err
+	}
+	m.watch = spectate.NewModel(conn, s)
+	return m, nil
+}
+
+// returns how long the game has been played, or how long it took once it is won
+func (m Model) Elapsed() time.Duration {
+	if m.gameWon {
+		return m.elapsed
+	}
+	return time.Since(m.started)
+}
+
+// returns the board being played, for tests and tools that look inside the game
+func (m Model) Board() board.Model {
+	return m.board
+}
+
+// records the game and every game after it with r
+func (m *Model) SetRecorder(r *replay.Recorder) {
+	m.recorder = r
+	m.board.SetRecording(true)
+	r.Game(m.options)
+}
+
+func sameCells(a, b []board.Cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// serves the game to spectators, the game must be seeded so they can generate its puzzle
+func (m *Model) SetSpectators(spectators *spectate.Server) {
+	m.spectators = spectators
 }
@@ -0,0 +1,333 @@
+package model_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/harness"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+)
+
+/*
+a 4x4 puzzle with 9 empty cells and its solution
+
+	21.. 2143
+	342. 3421
+	..3. 1234
+	..1. 4312
+*/
+const (
+	puzzle   = "21..342...3...1."
+	solution = "2143342112344312"
+)
+
+func newGame(t *testing.T) *harness.Harness {
+	h := harness.NewPuzzle(t, puzzle, settings.Settings{})
+	h.Resize(80, 30)
+	return h
+}
+
+func cell(row, col int) board.Cell {
+	return board.Cell{Row: row, Col: col}
+}
+
+// moves the cursor to c with the arrow keys, going down and right and wrapping around
+func moveTo(h *harness.Harness, c board.Cell) {
+	cursor, _ := h.Cursor()
+	for ; cursor.Row != c.Row; cursor.Row = (cursor.Row + 1) % 4 {
+		h.Keys("down")
+	}
+	for ; cursor.Col != c.Col; cursor.Col = (cursor.Col + 1) % 4 {
+		h.Keys("right")
+	}
+}
+
+// fills every empty cell with its value from solution, except those in wrong which get the
+// next value up
+func fill(h *harness.Harness, wrong ...board.Cell) {
+	for i, ch := range solution {
+		c := cell(i/4, i%4)
+		if puzzle[i] != '.' {
+			continue
+		}
+		moveTo(h, c)
+		val := ch
+		for _, w := range wrong {
+			if w == c {
+				val = '1' + (ch-'1'+1)%4
+			}
+		}
+		h.Keys(string(val))
+	}
+}
+
+func TestStart(t *testing.T) {
+	h := newGame(t)
+	h.AssertCursor(cell(0, 0))
+	h.AssertCellsLeft(9)
+	h.AssertUndo(0, 1)
+	h.AssertWrong()
+	if got := len(h.Board().Givens); got != 7 {
+		t.Errorf("%d givens, want 7", got)
+	}
+	h.Golden("start")
+}
+
+func TestSetCell(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(0, 2))
+	h.Keys("4")
+	h.AssertValue(cell(0, 2), 4)
+	h.AssertCellsLeft(8)
+	h.AssertUndo(1, 2)
+
+	// changing a value leaves the cells left alone
+	h.Keys("3")
+	h.AssertValue(cell(0, 2), 3)
+	h.AssertCellsLeft(8)
+	h.AssertUndo(2, 3)
+
+	// givens and values that don't fit the board do nothing, not even a board state
+	moveTo(h, cell(1, 0))
+	h.Keys("1", "5", "g")
+	h.AssertValue(cell(1, 0), 3)
+	h.AssertUndo(2, 3)
+
+	// every selected cell is set
+	moveTo(h, cell(3, 0))
+	h.Keys("shift+right", "shift+right")
+	h.Keys("4")
+	h.AssertValue(cell(3, 0), 4)
+	h.AssertValue(cell(3, 1), 4)
+	h.AssertValue(cell(3, 2), 1)
+	h.AssertCellsLeft(6)
+}
+
+func TestSetCellClearsPencils(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(0, 3))
+	h.Keys("$", "#")
+	moveTo(h, cell(3, 0))
+	h.Keys("$")
+	h.AssertPencils(cell(0, 3), 3, 4)
+
+	// a 4 in r1c3 rules 4 out of its row and box, r4c1 is neither
+	moveTo(h, cell(0, 2))
+	h.Keys("4")
+	h.AssertPencils(cell(0, 3), 3)
+	h.AssertPencils(cell(3, 0), 4)
+
+	// and the cell's own pencil marks stay for when it is emptied again
+	moveTo(h, cell(0, 3))
+	h.Keys("3")
+	h.AssertPencils(cell(0, 3), 3)
+	h.Golden("pencils")
+}
+
+func TestSetPencilCell(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(2, 0))
+	h.Keys("shift+right", "!", "@")
+	h.AssertPencils(cell(2, 0), 1, 2)
+	h.AssertPencils(cell(2, 1), 1, 2)
+	h.AssertUndo(2, 3)
+
+	// pencilling a mark again removes it
+	h.Keys("!")
+	h.AssertPencils(cell(2, 0), 2)
+	h.AssertPencils(cell(2, 1), 2)
+
+	// marks that don't fit the board, givens and cells with a value are left alone
+	h.Keys("%")
+	h.AssertUndo(3, 4)
+	moveTo(h, cell(0, 0))
+	h.Keys("!")
+	h.AssertPencils(cell(0, 0))
+	moveTo(h, cell(0, 2))
+	h.Keys("4", "!")
+	h.AssertPencils(cell(0, 2))
+	h.AssertUndo(4, 5)
+}
+
+func TestDeleteCell(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(0, 2))
+	h.Keys("4", "backspace")
+	h.AssertValue(cell(0, 2), -1)
+	h.AssertCellsLeft(9)
+	h.AssertUndo(2, 3)
+
+	// an empty cell loses its pencil marks
+	h.Keys("!", "@", "backspace")
+	h.AssertPencils(cell(0, 2))
+	h.AssertUndo(5, 6)
+
+	// nothing to delete, no board state
+	h.Keys("backspace")
+	moveTo(h, cell(1, 0))
+	h.Keys("backspace")
+	h.AssertValue(cell(1, 0), 3)
+	h.AssertUndo(5, 6)
+}
+
+func TestUndoRedo(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(0, 2))
+	h.Keys("4", "right", "3")
+	h.AssertUndo(2, 3)
+
+	h.Keys("ctrl+z")
+	h.AssertUndo(1, 3)
+	h.AssertValue(cell(0, 3), -1)
+	h.AssertValue(cell(0, 2), 4)
+	h.AssertCellsLeft(8)
+
+	h.Keys("ctrl+r")
+	h.AssertUndo(2, 3)
+	h.AssertValue(cell(0, 3), 3)
+	h.AssertCellsLeft(7)
+
+	// redo past the last state and undo past the first do nothing
+	h.Keys("ctrl+r")
+	h.AssertUndo(2, 3)
+	h.Keys("ctrl+z", "ctrl+z", "ctrl+z")
+	h.AssertUndo(0, 3)
+	h.AssertValue(cell(0, 2), -1)
+	h.AssertCellsLeft(9)
+}
+
+func TestUndoBranch(t *testing.T) {
+	h := newGame(t)
+	moveTo(h, cell(0, 2))
+	h.Keys("4", "right", "3", "ctrl+z")
+
+	// an edit after undoing drops the states that could be redone
+	h.Keys("2")
+	h.AssertUndo(2, 3)
+	h.AssertValue(cell(0, 3), 2)
+	h.Keys("ctrl+r")
+	h.AssertValue(cell(0, 3), 2)
+
+	h.Keys("ctrl+z")
+	h.AssertUndo(1, 3)
+	h.AssertValue(cell(0, 3), -1)
+	h.AssertValue(cell(0, 2), 4)
+}
+
+func TestCursorWrapAround(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want board.Cell
+	}{
+		{[]string{"up"}, cell(3, 0)},
+		{[]string{"left"}, cell(0, 3)},
+		{[]string{"k", "h"}, cell(3, 3)},
+		{[]string{"up", "left", "down", "right"}, cell(0, 0)},
+		{[]string{"right", "right", "right", "right"}, cell(0, 0)},
+		{[]string{"j", "j", "j", "j", "j"}, cell(1, 0)},
+	}
+	for _, tt := range tests {
+		h := newGame(t)
+		h.Keys(tt.keys...)
+		h.AssertCursor(tt.want)
+	}
+
+	// selections wrap around too, and moving without shift drops them
+	h := newGame(t)
+	h.Keys("shift+left", "shift+up")
+	if _, selected := h.Cursor(); len(selected) != 3 {
+		t.Errorf("selected %v, want r1c1, r1c4 and r4c4", selected)
+	}
+	h.AssertCursor(cell(3, 3))
+	h.Keys("left")
+	if _, selected := h.Cursor(); len(selected) != 1 || selected[0] != cell(3, 2) {
+		t.Errorf("selected %v after moving, want r4c3", selected)
+	}
+}
+
+func TestGameWon(t *testing.T) {
+	h := newGame(t)
+	fill(h)
+	h.AssertCellsLeft(0)
+	if !h.Won() {
+		t.Fatal("game not won with every cell filled in right")
+	}
+	won := false
+	for _, msg := range h.Messages() {
+		if _, ok := msg.(board.GameWon); ok {
+			won = true
+		}
+	}
+	if !won {
+		t.Errorf("no GameWon message in %v", h.Messages())
+	}
+	h.Golden("won")
+}
+
+func TestGameNotWon(t *testing.T) {
+	h := newGame(t)
+	fill(h, cell(2, 3))
+
+	// a full board is checked, the wrong cell is all that's left
+	if h.Won() {
+		t.Fatal("game won with a wrong cell")
+	}
+	h.AssertWrong(cell(2, 3))
+	h.AssertCellsLeft(1)
+	h.Golden("wrong")
+
+	// emptying the wrong cell still leaves just it
+	moveTo(h, cell(2, 3))
+	h.Keys("backspace")
+	h.AssertWrong()
+	h.AssertCellsLeft(1)
+
+	h.Keys("4")
+	h.AssertWrong()
+	if !h.Won() {
+		t.Error("game not won once the wrong cell is fixed")
+	}
+}
+
+// 4x4 medium cells are wider than their 2 pencil mark slots, so ascii mode has room to
+// mark the state of a cell on either side of its value
+func TestASCIILayouts(t *testing.T) {
+	for _, l := range []settings.Layout{settings.LayoutMedium, settings.LayoutCompact} {
+		h := harness.NewPuzzle(t, puzzle, settings.Settings{ASCII: true, Layout: l})
+		h.Resize(80, 30)
+		moveTo(h, cell(0, 2))
+		h.Keys("!", "@", "right", "3")
+		moveTo(h, cell(1, 0))
+		for _, want := range []string{">3<", "(2)", "(4)"} {
+			if !strings.Contains(h.View(), want) {
+				t.Errorf("%s layout doesn't show %s", l, want)
+			}
+		}
+		h.Golden("ascii-" + l.String())
+	}
+}
+
+// the size a layout asks for fits the side panels and menu as well as the board
+func TestTooSmall(t *testing.T) {
+	h := harness.NewPuzzle(t, puzzle, settings.Settings{Layout: settings.LayoutFull})
+	h.Resize(20, 10)
+	view := h.View()
+	i := strings.Index(view, "needs ")
+	if i < 0 {
+		t.Fatalf("a 20x10 window shows %q, want the size the layout needs", view)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(view[i:], "needs %dx%d", &width, &height); err != nil {
+		t.Fatal(err)
+	}
+	h.Resize(width, height)
+	if view := h.View(); strings.Contains(view, "Terminal too small") {
+		t.Errorf("a %dx%d window is still too small: %q", width, height, view)
+	}
+	h.Resize(width-1, height)
+	if view := h.View(); !strings.Contains(view, "Terminal too small") {
+		t.Errorf("a %dx%d window fits, but %dx%d was asked for", width-1, height, width, height)
+	}
+}
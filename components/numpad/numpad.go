@@ -0,0 +1,71 @@
+package numpad
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	DIGIT_COLOR    = lipgloss.Color("#F77F00")
+	COMPLETE_COLOR = lipgloss.Color("#5C5C5C")
+)
+
+// Side panel listing how many of each digit are still to be placed
+type Model struct {
+	digitsLeft []int // indexed by digit, index 0 is unused
+	settings   settings.Settings
+}
+
+func NewModel(s settings.Settings) Model {
+	return Model{
+		settings: s,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	return m, nil
+}
+
+// replaces the digit counts, called by the app model whenever the board changes
+func (m *Model) SetDigitsLeft(digitsLeft []int) {
+	m.digitsLeft = digitsLeft
+}
+
+// replaces the panel's display settings
+func (m *Model) SetSettings(s settings.Settings) {
+	m.settings = s
+}
+
+func (m Model) View() string {
+	rows := []string{"left"}
+	for num := 1; num < len(m.digitsLeft); num++ {
+		left := m.digitsLeft[num]
+		digit := board.DigitString(int8(num))
+		row := fmt.Sprintf("%s %2d", digit, left)
+
+		// complete digits are dimmed, in ascii mode we can't dim so they are crossed out instead
+		switch {
+		case left != 0:
+			if !m.settings.ASCII {
+				row = m.settings.NewStyle().Foreground(DIGIT_COLOR).Render(row)
+			}
+		case m.settings.ASCII:
+			row = fmt.Sprintf("%s  -", digit)
+		default:
+			row = m.settings.NewStyle().Foreground(COMPLETE_COLOR).Faint(true).Render(row)
+		}
+		rows = append(rows, row)
+	}
+
+	return strings.Join(rows, "\n")
+}
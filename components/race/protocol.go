@@ -0,0 +1,141 @@
+/*
+Package race plays a head-to-head race between two players over TCP. One player
+hosts, the other joins, and both solve the same puzzle, generated on each side
+from a seed the host picks. Each side sees the other's progress as they play,
+and the host judges who finished first.
+
+The protocol is one JSON object per line, every object has a type. Fields a
+message doesn't use are left out, and missing numbers are 0:
+
+	{"type":"start","seed":42,"mode":2,"size":9,"killer":true,"variants":["diagonal"],"symmetry":"rotational"}
+	    host to joiner, the first message after joining. Both players generate
+	    their puzzle from these game options, see board.GameOptions. Samurai
+	    games send "samurai":true
+	{"type":"progress","filled":40,"mistakes":1}
+	    both ways, whenever the sender's board changes. filled is the percent of
+	    the cells to fill that have a value, mistakes is how many values don't
+	    match the answer key
+	{"type":"done","contents":[{"row":0,"col":1,"val":5}]}
+	    joiner to host, the joiner's board is full and follows every rule. contents
+	    are its cells, see board.CellContents, and the host checks them against
+	    the puzzle's rules. A done whose board isn't solved is ignored
+	{"type":"result","winner":"join"}
+	    host to joiner once the race is over, the winner is "host" or "join"
+
+Messages of unknown types are ignored. Closing the connection leaves the race,
+if the joiner finished and the host leaves before sending a result the joiner wins.
+*/
+package race
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/wire"
+)
+
+// message types, see the package comment
+const (
+	typeStart    = "start"
+	typeProgress = "progress"
+	typeDone     = "done"
+	typeResult   = "result"
+)
+
+// winners of a result message
+const (
+	winnerHost = "host"
+	winnerJoin = "join"
+)
+
+// a message of the race protocol, see the package comment for which fields each type uses
+type Message struct {
+	Type string `json:"type"`
+
+	board.GameOptions // the game of a start message
+
+	Filled   int `json:"filled,omitempty"`
+	Mistakes int `json:"mistakes,omitempty"`
+
+	Contents []board.CellContents `json:"contents,omitempty"`
+
+	Winner string `json:"winner,omitempty"`
+}
+
+// a connection to the other player
+type Conn struct {
+	wire *wire.Conn
+}
+
+func newConn(c net.Conn) *Conn {
+	return &Conn{wire: wire.NewConn(c)}
+}
+
+func (c *Conn) Send(msg Message) error {
+	return c.wire.Send(msg)
+}
+
+// blocks until the next message arrives
+func (c *Conn) Receive() (Message, error) {
+	var msg Message
+	err := c.wire.Receive(&msg)
+	return msg, err
+}
+
+func (c *Conn) Close() error {
+	return c.wire.Close()
+}
+
+/*
+waits for a player to join on l, then sends them the game to race on. opts gets
+a random seed so both players generate the same puzzle, the returned options
+are the ones to play. Puzzle files can't be raced, only generated puzzles
+*/
+func Host(l net.Listener, opts board.GameOptions) (*Conn, board.GameOptions, error) {
+	c, err := l.Accept()
+	if err != nil {
+		return nil, opts, err
+	}
+	conn := newConn(c)
+
+	opts.Seed = time.Now().UnixNano()
+	if err := conn.Send(Message{Type: typeStart, GameOptions: opts}); err != nil {
+		conn.Close()
+		return nil, opts, err
+	}
+	return conn, opts, nil
+}
+
+// joins the race hosted at addr, returns the game options the host sent
+func Join(addr string) (*Conn, board.GameOptions, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, board.GameOptions{}, err
+	}
+	conn := newConn(c)
+
+	msg, err := conn.Receive()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, err
+	}
+	opts, err := msg.options()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, err
+	}
+	return conn, opts, nil
+}
+
+// returns the game options of a start message
+func (msg Message) options() (board.GameOptions, error) {
+	if msg.Type != typeStart {
+		return board.GameOptions{}, fmt.Errorf("host sent %q, want %q", msg.Type, typeStart)
+	}
+	if err := msg.GameOptions.Validate(); err != nil {
+		return board.GameOptions{}, fmt.Errorf("host sent a game we can't play: %w", err)
+	}
+	return msg.GameOptions, nil
+}
@@ -0,0 +1,224 @@
+package race
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	PROGRESS_COLOR = lipgloss.Color("#F77F00")
+	LEFT_COLOR     = lipgloss.Color("#5C5C5C")
+)
+
+// this is a tea.Msg type sent once the race is over, the app model shows the result
+type Finished struct {
+	Won bool
+}
+
+// a message from the opponent
+type receivedMsg struct {
+	msg Message
+}
+
+// the connection to the opponent broke or they left
+type disconnectedMsg struct {
+	err error
+}
+
+/*
+Side panel showing the opponent's progress in a race, and the race's side of the
+protocol. The app model reports our progress with SetProgress and our win with
+Finish, the model sends them to the opponent and reports the result with a
+Finished message. The host checks the joiner's finished board on the puzzle
+before the joiner wins. The zero Model is not in a race and does nothing
+*/
+type Model struct {
+	conn     *Conn
+	host     bool
+	start    board.Model // the board as the race started, the joiner's finish is judged on it
+	settings settings.Settings
+
+	sent     progress // our progress as last sent, we only send changes
+	opponent progress
+	waiting  bool  // we finished and are waiting for the host's result
+	over     bool  // the race has a winner
+	left     error // why the opponent is gone, nil while they are connected
+}
+
+// a player's percent of cells filled and count of wrong values
+type progress struct {
+	filled, mistakes int
+}
+
+// start is the board of the race's puzzle before anyone played on it
+func NewModel(conn *Conn, host bool, s settings.Settings, start board.Model) Model {
+	return Model{
+		conn:     conn,
+		host:     host,
+		start:    start.Copy(),
+		settings: s,
+		sent:     progress{filled: -1},
+	}
+}
+
+// returns true if we are in a race
+func (m Model) Racing() bool {
+	return m.conn != nil
+}
+
+// starts listening for the opponent's messages
+func (m Model) Init() tea.Cmd {
+	if !m.Racing() {
+		return nil
+	}
+	return m.receive()
+}
+
+// waits for the next message from the opponent
+func (m Model) receive() tea.Cmd {
+	conn := m.conn
+	return func() tea.Msg {
+		msg, err := conn.Receive()
+		if err != nil {
+			return disconnectedMsg{err}
+		}
+		return receivedMsg{msg}
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case receivedMsg:
+		switch msg.msg.Type {
+		case typeProgress:
+			m.opponent = progress{msg.msg.Filled, msg.msg.Mistakes}
+
+		// only the host judges, and only the first finish of a solved board counts
+		case typeDone:
+			if m.host && !m.over && m.start.Solves(msg.msg.Contents) {
+				m.over = true
+				m.opponent.filled = 100
+				m.send(Message{Type: typeResult, Winner: winnerJoin})
+				return m, tea.Batch(m.receive(), finished(false))
+			}
+
+		case typeResult:
+			if !m.host && !m.over {
+				m.over = true
+				won := msg.msg.Winner == winnerJoin
+				if !won {
+					m.opponent.filled = 100
+				}
+				return m, tea.Batch(m.receive(), finished(won))
+			}
+		}
+		return m, m.receive()
+
+	case disconnectedMsg:
+		m.left = msg.err
+		m.conn.Close()
+		// the host left without judging our finish, so nobody beat us
+		if m.waiting && !m.over {
+			m.over = true
+			return m, finished(true)
+		}
+	}
+
+	return m, nil
+}
+
+func finished(won bool) tea.Cmd {
+	return func() tea.Msg {
+		return Finished{Won: won}
+	}
+}
+
+// sends msg to the opponent, if it can't be sent they are treated as gone
+func (m *Model) send(msg Message) {
+	if m.left != nil {
+		return
+	}
+	if err := m.conn.Send(msg); err != nil {
+		m.left = err
+		m.conn.Close()
+	}
+}
+
+// sends our progress to the opponent if it changed, filled of total cells have a value
+func (m *Model) SetProgress(filled, total, mistakes int) {
+	if !m.Racing() {
+		return
+	}
+	p := progress{percent(filled, total), mistakes}
+	if p == m.sent {
+		return
+	}
+	m.sent = p
+	m.send(Message{Type: typeProgress, Filled: p.filled, Mistakes: p.mistakes})
+}
+
+/*
+called when our board is won, contents are its cells. The host judges the race,
+so if the joiner hasn't finished first the host wins right away. The joiner sends
+the host its board and waits for the result, unless the host is gone
+*/
+func (m *Model) Finish(contents []board.CellContents) tea.Cmd {
+	if !m.Racing() || m.over {
+		return nil
+	}
+	if m.host {
+		m.over = true
+		m.send(Message{Type: typeResult, Winner: winnerHost})
+		return finished(true)
+	}
+	if m.left != nil {
+		m.over = true
+		return finished(true)
+	}
+	m.waiting = true
+	m.send(Message{Type: typeDone, Contents: contents})
+	return nil
+}
+
+// replaces the panel's display settings
+func (m *Model) SetSettings(s settings.Settings) {
+	m.settings = s
+}
+
+func percent(n, total int) int {
+	if total == 0 {
+		return 100
+	}
+	return n * 100 / total
+}
+
+func (m Model) View() string {
+	if !m.Racing() {
+		return ""
+	}
+
+	rows := []string{"opponent"}
+	if m.left != nil && !m.over {
+		left := "left"
+		if !m.settings.ASCII {
+			left = m.settings.NewStyle().Foreground(LEFT_COLOR).Faint(true).Render(left)
+		}
+		return strings.Join(append(rows, left), "\n")
+	}
+
+	filled := fmt.Sprintf("%3d%%", m.opponent.filled)
+	if !m.settings.ASCII {
+		filled = m.settings.NewStyle().Foreground(PROGRESS_COLOR).Render(filled)
+	}
+	rows = append(rows, filled+" filled", fmt.Sprintf("%4d wrong", m.opponent.mistakes))
+	if m.waiting && !m.over {
+		rows = append(rows, "", "waiting for", "the host")
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,224 @@
+package race
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// how long a test waits for the race to get where it should
+const waitTimeout = 5 * time.Second
+
+// one side of a race, run the way Bubble Tea runs it: every command in its own
+// goroutine, and the messages they return fed back into the model in turn
+type player struct {
+	model    Model
+	board    board.Model
+	msgs     chan tea.Msg
+	finished []Finished // the results the model reported, in order
+}
+
+func newPlayer(t *testing.T, conn *Conn, opts board.GameOptions, host bool) *player {
+	t.Helper()
+	t.Cleanup(func() { conn.Close() })
+	b, err := board.NewModel(opts, settings.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &player{model: NewModel(conn, host, settings.Settings{}, b), board: b, msgs: make(chan tea.Msg, 100)}
+	p.run(p.model.Init())
+	return p
+}
+
+// runs cmd in the background, batches are taken apart like Bubble Tea does
+func (p *player) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		msg := cmd()
+		if batch := reflect.ValueOf(msg); batch.Kind() == reflect.Slice && batch.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+			for i := 0; i < batch.Len(); i++ {
+				p.run(batch.Index(i).Interface().(tea.Cmd))
+			}
+			return
+		}
+		if msg != nil {
+			p.msgs <- msg
+		}
+	}()
+}
+
+// feeds the model messages until done returns true, failing the test if it takes too long
+func (p *player) until(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	timeout := time.After(waitTimeout)
+	for !done() {
+		select {
+		case msg := <-p.msgs:
+			if f, ok := msg.(Finished); ok {
+				p.finished = append(p.finished, f)
+				continue
+			}
+			var cmd tea.Cmd
+			p.model, cmd = p.model.Update(msg)
+			p.run(cmd)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// returns the contents of b with every cell filled in from its solution
+func solved(t *testing.T, b board.Model) []board.CellContents {
+	t.Helper()
+	cells := b.Snapshot().Cells
+	var line strings.Builder
+	for _, row := range cells {
+		for _, c := range row {
+			if c.Val == -1 {
+				line.WriteString(".")
+			} else {
+				line.WriteString(board.DigitString(c.Val))
+			}
+		}
+	}
+	p, err := board.ParsePuzzleLine(line.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, solution := p.Solve(2)
+	if n != 1 {
+		t.Fatalf("%s has %d solutions, want 1", line.String(), n)
+	}
+	contents := b.Contents()
+	for i, c := range contents {
+		contents[i].Val = int8(strings.Index("123456789ABCDEFG", string(solution[c.Row*len(cells)+c.Col])) + 1)
+	}
+	return contents
+}
+
+// starts a race of a 4x4 puzzle on the loopback interface
+func startRace(t *testing.T) (host, join *player) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	type hosted struct {
+		conn *Conn
+		opts board.GameOptions
+		err  error
+	}
+	hosting := make(chan hosted, 1)
+	go func() {
+		conn, opts, err := Host(l, board.GameOptions{Size: 4, Mode: 2, Symmetry: "rotational"})
+		hosting <- hosted{conn, opts, err}
+	}()
+	joinConn, joinOpts, err := Join(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := <-hosting
+	if h.err != nil {
+		t.Fatal(h.err)
+	}
+
+	if h.opts.Seed == 0 {
+		t.Error("host picked no seed")
+	}
+	if !reflect.DeepEqual(h.opts, joinOpts) {
+		t.Fatalf("host plays %+v, joiner %+v", h.opts, joinOpts)
+	}
+	host, join = newPlayer(t, h.conn, h.opts, true), newPlayer(t, joinConn, joinOpts, false)
+	if !reflect.DeepEqual(host.board.Contents(), join.board.Contents()) {
+		t.Fatal("host and joiner generated different puzzles")
+	}
+	return host, join
+}
+
+func TestProgress(t *testing.T) {
+	host, join := startRace(t)
+
+	join.model.SetProgress(3, 10, 1)
+	host.until(t, "the joiner's progress", func() bool { return host.model.opponent == progress{30, 1} })
+	host.model.SetProgress(5, 10, 0)
+	join.until(t, "the host's progress", func() bool { return join.model.opponent == progress{50, 0} })
+
+	// only changes are sent
+	join.model.SetProgress(3, 10, 1)
+	join.model.SetProgress(4, 10, 2)
+	host.until(t, "the joiner's progress", func() bool { return host.model.opponent == progress{40, 2} })
+}
+
+func TestHostFinishesFirst(t *testing.T) {
+	host, join := startRace(t)
+
+	host.run(host.model.Finish(solved(t, host.board)))
+	host.until(t, "the host's result", func() bool { return len(host.finished) > 0 })
+	join.until(t, "the joiner's result", func() bool { return len(join.finished) > 0 })
+	if !host.finished[0].Won || join.finished[0].Won {
+		t.Errorf("host got %+v and joiner %+v, want the host to win", host.finished, join.finished)
+	}
+
+	// finishing after the race is over changes nothing
+	if cmd := join.model.Finish(solved(t, join.board)); cmd != nil {
+		t.Error("joiner finished a race that was over")
+	}
+}
+
+func TestJoinerFinishesFirst(t *testing.T) {
+	host, join := startRace(t)
+
+	join.run(join.model.Finish(solved(t, join.board)))
+	host.until(t, "the host's result", func() bool { return len(host.finished) > 0 })
+	join.until(t, "the joiner's result", func() bool { return len(join.finished) > 0 })
+	if host.finished[0].Won || !join.finished[0].Won {
+		t.Errorf("host got %+v and joiner %+v, want the joiner to win", host.finished, join.finished)
+	}
+
+	// the host finishing second doesn't win
+	if cmd := host.model.Finish(solved(t, host.board)); cmd != nil {
+		t.Error("host finished a race that was over")
+	}
+}
+
+func TestUnsolvedDone(t *testing.T) {
+	host, join := startRace(t)
+
+	// a done with a board that isn't full, or full and wrong, doesn't win
+	wrong := solved(t, join.board)
+	for i := range wrong {
+		if wrong[i].Val != join.board.Contents()[i].Val {
+			wrong[i].Val = wrong[i].Val%4 + 1
+			break
+		}
+	}
+	for _, contents := range [][]board.CellContents{nil, join.board.Contents(), wrong} {
+		if err := join.model.conn.Send(Message{Type: typeDone, Contents: contents}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// progress sent after the dones arrives after them, once it has they were all handled
+	join.model.SetProgress(1, 10, 0)
+	host.until(t, "the joiner's progress", func() bool { return host.model.opponent == progress{10, 0} })
+	if host.model.over || len(host.finished) > 0 {
+		t.Fatalf("host judged a board that isn't solved, got %+v", host.finished)
+	}
+
+	// and the host can still win
+	host.run(host.model.Finish(solved(t, host.board)))
+	host.until(t, "the host's result", func() bool { return len(host.finished) > 0 })
+	if !host.finished[0].Won {
+		t.Error("host didn't win after ignoring the joiner's unsolved board")
+	}
+}
@@ -0,0 +1,83 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// moves the cursor home and clears the screen before every frame
+const clearScreen = "\x1b[H\x1b[J"
+
+// the header line of an asciicast v2 file, see https://docs.asciinema.org/manual/asciicast/v2/
+type castHeader struct {
+	Version int               `json:"version"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Env     map[string]string `json:"env"`
+}
+
+// a frame of the board and when it was drawn
+type frame struct {
+	t    int64
+	view string
+}
+
+/*
+Writes the replay's events to w as an asciinema v2 recording, so a game can be
+shared as a terminal recording. Every event that changes the board becomes a
+frame at the event's time, drawn by board.View like the game draws it. The auto
+layout draws in the medium layout, there is no window to fit
+*/
+func WriteCast(w io.Writer, events []Event, s settings.Settings) error {
+	if s.Layout == settings.LayoutAuto {
+		s.Layout = settings.LayoutMedium
+	}
+	m, err := NewModel(events, s)
+	if err != nil {
+		return err
+	}
+
+	var frames []frame
+	width, height := 0, 0
+	for n := 1; n <= len(events); n++ {
+		m.goTo(n)
+		view := m.board.View()
+		if len(frames) > 0 && frames[len(frames)-1].view == view {
+			continue
+		}
+		frames = append(frames, frame{t: events[n-1].T, view: view})
+		if lipgloss.Width(view) > width {
+			width = lipgloss.Width(view)
+		}
+		if lipgloss.Height(view) > height {
+			height = lipgloss.Height(view)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(castHeader{
+		Version: 2,
+		Width:   width,
+		Height:  height,
+		Env:     map[string]string{"TERM": "xterm-256color"},
+	}); err != nil {
+		return err
+	}
+	// frames start when the game does, the first event isn't always at 0
+	start := frames[0].t
+	for _, f := range frames {
+		data := clearScreen + strings.ReplaceAll(f.view, "\n", "\r\n")
+		if err := enc.Encode([]any{float64(f.t-start) / 1000, "o", data}); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
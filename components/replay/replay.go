@@ -0,0 +1,171 @@
+/*
+Package replay records games and plays them back. A replay file is one JSON
+object per line, every object has a type and t, the milliseconds since the
+recording started. Fields a line doesn't use are left out:
+
+	{"type":"game","t":0,"seed":42,"mode":2,"size":9,"killer":true,"variants":["diagonal"],"symmetry":"rotational"}
+	    a game starts, see board.GameOptions. Games played from a puzzle file
+	    carry the file in "puzzle" instead, so the replay needs nothing else.
+	    A recording starts with a game, and has another one for every new game
+	{"type":"edit","t":5120,"edit":{"op":"set","cells":[{"row":0,"col":3}],"val":5}}
+	    a set, pencil, delete, undo or redo, see board.Edit
+	{"type":"cursor","t":5480,"cursor":{"row":0,"col":4},"selected":[{"row":0,"col":4}]}
+	    the cursor moved or the selection changed
+
+Lines of unknown types are skipped.
+*/
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+)
+
+// line types, see the package comment
+const (
+	typeGame   = "game"
+	typeEdit   = "edit"
+	typeCursor = "cursor"
+)
+
+// a line of a replay file, see the package comment for which fields each type uses
+type Event struct {
+	Type string `json:"type"`
+	T    int64  `json:"t"`
+
+	board.GameOptions        // the game of a game line
+	Puzzle            string `json:"puzzle,omitempty"`
+
+	Edit *board.Edit `json:"edit,omitempty"`
+
+	Cursor   *board.Cell  `json:"cursor,omitempty"`
+	Selected []board.Cell `json:"selected,omitempty"`
+}
+
+// returns the game options of a game line
+func (e Event) options() (board.GameOptions, error) {
+	if e.Puzzle != "" {
+		puzzle, err := board.ParsePuzzle(strings.NewReader(e.Puzzle))
+		if err != nil {
+			return board.GameOptions{}, err
+		}
+		return board.GameOptions{Size: puzzle.Size(), Puzzle: &puzzle}, nil
+	}
+
+	if e.Seed == 0 {
+		return board.GameOptions{}, fmt.Errorf("a game without a seed, we can't generate it")
+	}
+	if err := e.GameOptions.Validate(); err != nil {
+		return board.GameOptions{}, err
+	}
+	return e.GameOptions, nil
+}
+
+/*
+Writes a game to a replay file as it is played. The app model passes on the
+board's edits and cursor moves, and the games it starts. Every line is written
+straight away, so a game that crashes or is killed still leaves its replay. The
+first error stops the recording and is returned by Close
+*/
+type Recorder struct {
+	file   *os.File
+	start  time.Time
+	puzzle string // the puzzle file played, written with every game
+	err    error
+}
+
+// creates the replay file at path. puzzle is the text of the puzzle file being
+// played, empty for generated puzzles, which are generated again from their seed
+func NewRecorder(path string, puzzle string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: f, start: time.Now(), puzzle: puzzle}, nil
+}
+
+// records the start of a game of opts
+func (r *Recorder) Game(opts board.GameOptions) {
+	if r.puzzle != "" {
+		r.write(Event{Type: typeGame, Puzzle: r.puzzle})
+		return
+	}
+	r.write(Event{Type: typeGame, GameOptions: opts})
+}
+
+// records an edit the board made
+func (r *Recorder) Edit(e board.Edit) {
+	r.write(Event{Type: typeEdit, Edit: &e})
+}
+
+// records the cursor and selection after they changed
+func (r *Recorder) Cursor(cursor board.Cell, selected []board.Cell) {
+	r.write(Event{Type: typeCursor, Cursor: &cursor, Selected: selected})
+}
+
+func (r *Recorder) write(e Event) {
+	if r.err != nil {
+		return
+	}
+	e.T = time.Since(r.start).Milliseconds()
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.file.Write(append(line, '\n'))
+	}
+	r.err = err
+}
+
+// closes the replay file, returns the first error the recording ran into
+func (r *Recorder) Close() error {
+	err := r.file.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// reads a replay file, the lines are returned in order with their game options checked
+func Load(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	// puzzle files are written on a single line
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case e.Type == typeGame:
+			if _, err := e.options(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		case e.Type == typeEdit && e.Edit != nil, e.Type == typeCursor && e.Cursor != nil:
+		default:
+			continue
+		}
+		if len(events) == 0 && e.Type != typeGame {
+			return nil, fmt.Errorf("line %d: a replay has to start with a game", line)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s has no game", path)
+	}
+	return events, nil
+}
@@ -0,0 +1,290 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/inputs"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	tickInterval    = 50 * time.Millisecond // how often the clock moves while playing
+	seekStep        = 10 * time.Second
+	statusSeparator = "\n\n" // blank lines between the board and the status line
+)
+
+// playback speeds, a replay starts at 1x
+var speeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16}
+
+const normalSpeed = 2
+
+// moves the clock while playing, id tells ticks of an earlier play apart
+type tickMsg struct {
+	id int
+}
+
+/*
+Plays back a replay file on a board drawn like the game's. The clock runs at
+the chosen speed while playing and every event up to it is applied. Going back
+starts over from a copy of the game's starting board, applying events up to the
+point sought. Starting boards are generated once, when the replay is loaded
+*/
+type Model struct {
+	events   []Event
+	games    map[int]board.Model // starting boards of the game lines, by index
+	settings settings.Settings
+	board    board.Model
+	help     help.Model
+
+	next    int   // index of the next event to apply, events before it are on the board
+	clock   int64 // milliseconds into the replay
+	playing bool
+	speed   int // index into speeds
+	tick    int // id of the running ticks
+
+	width, height int
+}
+
+// makes a viewer for a replay's events as returned by Load, paused at its start
+func NewModel(events []Event, s settings.Settings) (Model, error) {
+	m := Model{
+		events:   events,
+		games:    make(map[int]board.Model),
+		settings: s,
+		help:     help.New(),
+		speed:    normalSpeed,
+	}
+	for i, e := range events {
+		if e.Type != typeGame {
+			continue
+		}
+		opts, err := e.options()
+		if err != nil {
+			return Model{}, err
+		}
+		if m.games[i], err = board.NewModel(opts, s); err != nil {
+			return Model{}, err
+		}
+	}
+	m.goTo(1)
+	m.clock = 0
+	return m, nil
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.help.Width = msg.Width
+
+	case tickMsg:
+		if !m.playing || msg.id != m.tick {
+			break
+		}
+		m.clock += int64(float64(tickInterval.Milliseconds()) * speeds[m.speed])
+		for m.next < len(m.events) && m.events[m.next].T <= m.clock {
+			m.apply(m.next)
+			m.next++
+		}
+		if m.next == len(m.events) {
+			m.playing = false
+			m.clock = m.end()
+			break
+		}
+		cmd = m.ticks()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, inputs.Controls.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, inputs.ReplayControls.PlayPause):
+			m.playing = !m.playing
+			if m.playing {
+				// playing from the end starts over
+				if m.next == len(m.events) {
+					m.goTo(1)
+					m.clock = 0
+				}
+				m.tick++
+				cmd = m.ticks()
+			}
+
+		case key.Matches(msg, inputs.ReplayControls.Faster):
+			if m.speed < len(speeds)-1 {
+				m.speed++
+			}
+
+		case key.Matches(msg, inputs.ReplayControls.Slower):
+			if m.speed > 0 {
+				m.speed--
+			}
+
+		case key.Matches(msg, inputs.ReplayControls.StepForward):
+			m.playing = false
+			if m.next < len(m.events) {
+				m.goTo(m.next + 1)
+			}
+
+		case key.Matches(msg, inputs.ReplayControls.StepBack):
+			m.playing = false
+			if m.next > 1 {
+				m.goTo(m.next - 1)
+			}
+
+		case key.Matches(msg, inputs.ReplayControls.SeekForward):
+			m.seek(m.clock + seekStep.Milliseconds())
+
+		case key.Matches(msg, inputs.ReplayControls.SeekBack):
+			m.seek(m.clock - seekStep.Milliseconds())
+
+		case key.Matches(msg, inputs.ReplayControls.SeekPercent):
+			tenths := int64(msg.String()[0] - '0')
+			m.seek(m.end() * tenths / 10)
+
+		case key.Matches(msg, inputs.ReplayControls.Start):
+			m.seek(0)
+
+		case key.Matches(msg, inputs.ReplayControls.End):
+			m.seek(m.end())
+
+		case key.Matches(msg, inputs.Controls.Help):
+			m.help.ShowAll = !m.help.ShowAll
+
+		case key.Matches(msg, inputs.Controls.Colorblind):
+			m.settings.Colorblind = !m.settings.Colorblind
+			m.board.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.Layout):
+			m.settings.Layout = m.settings.Layout.Next()
+			m.board.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.HighlightPeers):
+			m.settings.HighlightPeers = !m.settings.HighlightPeers
+			m.board.SetSettings(m.settings)
+
+		case key.Matches(msg, inputs.Controls.HighlightDigits):
+			m.settings.HighlightDigits = !m.settings.HighlightDigits
+			m.board.SetSettings(m.settings)
+		}
+	}
+
+	m.resize()
+	return m, cmd
+}
+
+// returns a command moving the clock after a tick
+func (m Model) ticks() tea.Cmd {
+	id := m.tick
+	return tea.Tick(tickInterval, func(time.Time) tea.Msg {
+		return tickMsg{id}
+	})
+}
+
+// applies the event at index i to the board
+func (m *Model) apply(i int) {
+	e := m.events[i]
+	switch e.Type {
+	case typeGame:
+		m.board = m.games[i].Copy()
+		m.board.SetSettings(m.settings)
+	case typeEdit:
+		m.board.ApplyEdit(*e.Edit)
+	case typeCursor:
+		m.board.SetCursor(*e.Cursor, e.Selected)
+	}
+}
+
+// puts the board where it is after the first n events, n is at least 1 for the first game.
+// Going forward applies the events in between, going back starts the game over
+func (m *Model) goTo(n int) {
+	start := m.next
+	if n < m.next {
+		start = n - 1
+		for m.events[start].Type != typeGame {
+			start--
+		}
+	}
+	for i := start; i < n; i++ {
+		m.apply(i)
+	}
+	m.next = n
+	m.clock = m.events[n-1].T
+}
+
+// moves the clock to t and puts the board where it is then, playing carries on from there
+func (m *Model) seek(t int64) {
+	if t < 0 {
+		t = 0
+	} else if t > m.end() {
+		t = m.end()
+	}
+	n := 1
+	for n < len(m.events) && m.events[n].T <= t {
+		n++
+	}
+	m.goTo(n)
+	m.clock = t
+}
+
+// returns the time of the last event
+func (m Model) end() int64 {
+	return m.events[len(m.events)-1].T
+}
+
+// gives the board whatever room the status line and help leave it
+func (m *Model) resize() {
+	m.board.SetSize(m.width, m.height-m.footerHeight())
+}
+
+// returns the height of the status line and help under the board
+func (m Model) footerHeight() int {
+	return lipgloss.Height(statusSeparator + m.status() + "\n" + m.help.View(inputs.ReplayControls))
+}
+
+// returns the status line, whether we are playing, the speed and how far in we are
+func (m Model) status() string {
+	state := "paused"
+	switch {
+	case m.playing:
+		state = "playing"
+	case m.next == len(m.events):
+		state = "done"
+	}
+	return fmt.Sprintf("%s  %gx  %s / %s  event %d/%d",
+		state, speeds[m.speed], clockTime(m.clock), clockTime(m.end()), m.next, len(m.events))
+}
+
+// formats milliseconds as m:ss
+func clockTime(ms int64) string {
+	s := ms / 1000
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func (m Model) View() string {
+	if m.board.TooSmall() {
+		w, h := m.board.Size()
+		compositeView := lipgloss.JoinVertical(lipgloss.Center,
+			"Terminal too small",
+			fmt.Sprintf("The %s layout needs %dx%d, have %dx%d", m.board.Layout(), w, h+m.footerHeight(), m.width, m.height),
+			"Press 'v' to change layout or 'q' to quit")
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
+	}
+
+	compositeView := lipgloss.JoinVertical(lipgloss.Center,
+		m.board.View()+statusSeparator+m.status(),
+		m.help.View(inputs.ReplayControls))
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, compositeView)
+}
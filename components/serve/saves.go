@@ -0,0 +1,94 @@
+package serve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	model "github.com/Alex-Merrill/sudoku-tui/components"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+
+	"github.com/gliderlabs/ssh"
+)
+
+// the players' unfinished games, one JSON file per public key in dir
+type saves struct {
+	dir string
+}
+
+// an unfinished game, the puzzle is generated again from the options and seed
+type save struct {
+	board.GameOptions
+	Contents []board.CellContents `json:"contents"`
+}
+
+// returns the options of the saved game, or an error if we can't play it
+func (s save) options() (board.GameOptions, error) {
+	if s.Seed == 0 {
+		return board.GameOptions{}, fmt.Errorf("saved game has no seed, we can't generate it")
+	}
+	if err := s.GameOptions.Validate(); err != nil {
+		return board.GameOptions{}, fmt.Errorf("saved game we can't play: %w", err)
+	}
+	return s.GameOptions, nil
+}
+
+// returns the file of key's save, the hash of the key keeps the name short and safe
+func (s saves) path(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// returns key's saved game, or nil if they have none
+func (s saves) load(key ssh.PublicKey) (*save, error) {
+	if key == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var saved save
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// saves the game of m under key. Finished games and games without a seed,
+// which can't be generated again, remove the save instead
+func (s saves) store(key ssh.PublicKey, m model.Model) error {
+	if key == nil {
+		return nil
+	}
+	opts, contents, over := m.Game()
+	if over || opts.Seed == 0 || opts.Puzzle != nil {
+		err := os.Remove(s.path(key))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.Marshal(save{GameOptions: opts, Contents: contents})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	// write to a temporary file first, so a failed write never leaves half a save
+	tmp := s.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(key))
+}
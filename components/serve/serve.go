@@ -0,0 +1,211 @@
+/*
+Package serve hosts the game over SSH, so players can `ssh` in and play without
+installing anything. Every session runs its own program and app model on the
+session's PTY, sized from its window, and players are told apart by their SSH
+public key: a game that isn't finished when the session ends is saved under the
+player's key and picked up again the next time they connect.
+*/
+package serve
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/Alex-Merrill/sudoku-tui/components"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/wish"
+	"github.com/charmbracelet/wish/activeterm"
+	"github.com/charmbracelet/wish/logging"
+	"github.com/gliderlabs/ssh"
+	"github.com/muesli/termenv"
+)
+
+// how a server plays its sessions
+type Options struct {
+	Game        board.GameOptions // the game every new session starts, puzzle files can't be served
+	Settings    settings.Settings
+	Dir         string        // holds the host key and the players' saves, made if missing
+	IdleTimeout time.Duration // sessions without input for this long are closed, 0 for never
+	MaxSessions int           // sessions allowed at once, 0 for no limit
+}
+
+type server struct {
+	opts  Options
+	saves saves
+
+	mu       sync.Mutex
+	sessions int
+}
+
+// serves the game on addr until the server fails
+func ListenAndServe(addr string, opts Options) error {
+	srv, err := newServer(addr, opts)
+	if err != nil {
+		return err
+	}
+	return srv.ListenAndServe()
+}
+
+// returns the SSH server playing the game of opts on addr
+func newServer(addr string, opts Options) (*ssh.Server, error) {
+	s := &server{opts: opts, saves: saves{dir: filepath.Join(opts.Dir, "saves")}}
+	return wish.NewServer(
+		wish.WithAddress(addr),
+		wish.WithHostKeyPath(filepath.Join(opts.Dir, "host_ed25519")),
+		// any key may play, the key only picks the player's saves
+		wish.WithPublicKeyAuth(func(ssh.Context, ssh.PublicKey) bool { return true }),
+		wish.WithIdleTimeout(opts.IdleTimeout),
+		// run last to first
+		wish.WithMiddleware(
+			s.play,
+			activeterm.Middleware(),
+			s.limit,
+			logging.Middleware(),
+		),
+	)
+}
+
+// turns sessions away while MaxSessions are running
+func (s *server) limit(next ssh.Handler) ssh.Handler {
+	return func(sess ssh.Session) {
+		s.mu.Lock()
+		full := s.opts.MaxSessions > 0 && s.sessions >= s.opts.MaxSessions
+		if !full {
+			s.sessions++
+		}
+		s.mu.Unlock()
+
+		if full {
+			fmt.Fprintln(sess, "The server is full, try again later")
+			sess.Exit(1)
+			return
+		}
+		defer func() {
+			s.mu.Lock()
+			s.sessions--
+			s.mu.Unlock()
+		}()
+		next(sess)
+	}
+}
+
+/*
+runs the game on a session until the player quits or the session ends, then
+saves it under the player's key. Like wish's bubbletea middleware, but it keeps
+the final model so the game can be saved
+*/
+func (s *server) play(next ssh.Handler) ssh.Handler {
+	return func(sess ssh.Session) {
+		m, err := s.newModel(sess.PublicKey(), s.settings(sess))
+		if err != nil {
+			log.Printf("%s: %v", sess.RemoteAddr(), err)
+			fmt.Fprintf(sess, "Could not start the game: %v\n", err)
+			sess.Exit(1)
+			return
+		}
+
+		p := tea.NewProgram(m, tea.WithInput(sess), tea.WithOutput(sess), tea.WithAltScreen())
+		// the first window is the PTY's, then every resize. Send blocks once the
+		// program has exited, so nothing is sent after done is closed
+		_, windows, _ := sess.Pty()
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case w, ok := <-windows:
+					if !ok {
+						windows = nil // the session is ending, wait for its context
+						continue
+					}
+					p.Send(tea.WindowSizeMsg{Width: w.Width, Height: w.Height})
+				case <-sess.Context().Done():
+					select {
+					case <-done:
+					default:
+						p.Quit()
+					}
+					return
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		final, err := p.StartReturningModel()
+		close(done)
+		if err != nil {
+			log.Printf("%s: %v", sess.RemoteAddr(), err)
+		}
+		if final, ok := final.(model.Model); ok {
+			if err := s.saves.store(sess.PublicKey(), final); err != nil {
+				log.Printf("%s: saving: %v", sess.RemoteAddr(), err)
+			}
+		}
+		next(sess)
+	}
+}
+
+/*
+returns the settings a session is drawn with. Every session gets a renderer of its
+own, with the color profile of the terminal its PTY names, so players with different
+terminals don't change each other's colors. Sessions that set NO_COLOR are drawn plain
+*/
+func (s *server) settings(sess ssh.Session) settings.Settings {
+	pty, _, _ := sess.Pty()
+	env := sessionEnv(append(sess.Environ(), "TERM="+pty.Term))
+	r := lipgloss.NewRenderer(sess, termenv.WithEnvironment(env), termenv.WithTTY(true))
+	// asking the terminal for its background would read the player's keys
+	r.SetHasDarkBackground(true)
+
+	st := s.opts.Settings
+	st.ASCII = st.ASCII || r.Output().EnvNoColor()
+	if st.ASCII {
+		r.SetColorProfile(termenv.Ascii)
+	}
+	st.Renderer = r
+	return st
+}
+
+// the environment variables a session's client sent, as termenv looks them up
+type sessionEnv []string
+
+func (e sessionEnv) Environ() []string {
+	return e
+}
+
+// returns the value of key, the last one if it is set more than once
+func (e sessionEnv) Getenv(key string) string {
+	for i := len(e) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(e[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// returns the player's saved game, or a new game if they have none, drawn with st
+func (s *server) newModel(key ssh.PublicKey, st settings.Settings) (model.Model, error) {
+	saved, err := s.saves.load(key)
+	if err == nil && saved != nil {
+		var opts board.GameOptions
+		if opts, err = saved.options(); err == nil {
+			return model.NewSavedModel(opts, st, saved.Contents)
+		}
+	}
+	if err != nil {
+		log.Printf("loading save: %v", err)
+	}
+
+	// a seed lets the game be saved and generated again
+	opts := s.opts.Game
+	opts.Seed = time.Now().UnixNano()
+	return model.NewModel(opts, st)
+}
@@ -0,0 +1,295 @@
+package serve
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/Alex-Merrill/sudoku-tui/components"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/muesli/termenv"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// how long a test waits for a session to get where it should
+const waitTimeout = 5 * time.Second
+
+// serves 4x4 games on the loopback interface from a temporary directory,
+// returns the players' saves and the server's address
+func serve(t *testing.T, opts Options) (saves, string) {
+	t.Helper()
+	opts.Game = board.GameOptions{Size: 4, Mode: 2}
+	opts.Dir = t.TempDir()
+	srv, err := newServer("127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	go srv.Serve(l)
+	return saves{dir: filepath.Join(opts.Dir, "saves")}, l.Addr().String()
+}
+
+func newKey(t *testing.T) gossh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// a player's SSH session, with everything the server wrote to it
+type client struct {
+	sess  *gossh.Session
+	stdin io.Writer
+
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (c *client) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+// opens a session on an xterm-256color PTY
+func connect(t *testing.T, addr string, key gossh.Signer) *client {
+	t.Helper()
+	conn, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            "player",
+		Auth:            []gossh.AuthMethod{gossh.PublicKeys(key)},
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		Timeout:         waitTimeout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	sess, err := conn.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{sess: sess}
+	sess.Stdout = c
+	if c.stdin, err = sess.StdinPipe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.RequestPty("xterm-256color", 24, 80, gossh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waits until the server has written text, failing the test if it takes too long
+func (c *client) until(t *testing.T, text string) {
+	t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		c.mu.Lock()
+		found := strings.Contains(c.out.String(), text)
+		c.mu.Unlock()
+		if found {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q, got %q", text, c.out.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// waits for the session to end, returns its exit status
+func (c *client) wait(t *testing.T) int {
+	t.Helper()
+	ended := make(chan error, 1)
+	go func() { ended <- c.sess.Wait() }()
+	select {
+	case err := <-ended:
+		var exit *gossh.ExitError
+		if errors.As(err, &exit) {
+			return exit.ExitStatus()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		return 0
+	case <-time.After(waitTimeout):
+		t.Fatal("the session didn't end")
+	}
+	return 0
+}
+
+// quits the game and waits for the session to end
+func (c *client) quit(t *testing.T) {
+	t.Helper()
+	c.until(t, "new game")
+	if _, err := c.stdin.Write([]byte("q")); err != nil {
+		t.Fatal(err)
+	}
+	if status := c.wait(t); status != 0 {
+		t.Fatalf("session exited with %d", status)
+	}
+}
+
+func loadSave(t *testing.T, s saves, key gossh.Signer) *save {
+	t.Helper()
+	saved, err := s.load(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestResume(t *testing.T) {
+	s, addr := serve(t, Options{})
+	key := newKey(t)
+
+	connect(t, addr, key).quit(t)
+	first := loadSave(t, s, key)
+	if first == nil || first.Seed == 0 || first.Size != 4 {
+		t.Fatalf("saved %+v, want the unfinished 4x4 game", first)
+	}
+
+	// the next session plays the saved game, not a new one
+	connect(t, addr, key).quit(t)
+	if second := loadSave(t, s, key); second == nil || second.Seed != first.Seed {
+		t.Fatalf("saved %+v after resuming, want seed %d", second, first.Seed)
+	}
+
+	// another key has a game of its own
+	other := newKey(t)
+	connect(t, addr, other).quit(t)
+	if saved := loadSave(t, s, other); saved == nil || saved.Seed == first.Seed {
+		t.Fatalf("another player saved %+v, want a game of their own", saved)
+	}
+}
+
+func TestUnplayableSave(t *testing.T) {
+	s, addr := serve(t, Options{})
+	key := newKey(t)
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.path(key.PublicKey()), []byte(`{"size":5,"seed":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the player gets a new game, which replaces the save
+	connect(t, addr, key).quit(t)
+	if saved := loadSave(t, s, key); saved == nil || saved.Size != 4 || saved.Seed == 1 {
+		t.Fatalf("saved %+v, want a new 4x4 game", saved)
+	}
+}
+
+func TestStoreRemoves(t *testing.T) {
+	s := saves{dir: t.TempDir()}
+	key := newKey(t).PublicKey()
+	seeded, err := model.NewModel(board.GameOptions{Size: 4, Seed: 1}, settings.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store(key, seeded); err != nil {
+		t.Fatal(err)
+	}
+
+	// a game without a seed can't be generated again, so it takes the save's place as nothing
+	unseeded, err := model.NewModel(board.GameOptions{Size: 4}, settings.Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store(key, unseeded); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := s.load(key); err != nil || saved != nil {
+		t.Fatalf("loaded %+v, %v after storing an unseeded game, want no save", saved, err)
+	}
+	if err := s.store(key, unseeded); err != nil {
+		t.Errorf("removing a save that isn't there: %v", err)
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	_, addr := serve(t, Options{MaxSessions: 1})
+	key := newKey(t)
+
+	playing := connect(t, addr, key)
+	playing.until(t, "new game")
+	turnedAway := connect(t, addr, key)
+	turnedAway.until(t, "The server is full")
+	if status := turnedAway.wait(t); status != 1 {
+		t.Errorf("turned away session exited with %d, want 1", status)
+	}
+	playing.quit(t)
+}
+
+// a session with only the PTY and environment a renderer is built from
+type fakeSession struct {
+	ssh.Session
+	term string
+	env  []string
+}
+
+func (s fakeSession) Pty() (ssh.Pty, <-chan ssh.Window, bool) {
+	return ssh.Pty{Term: s.term}, nil, true
+}
+
+func (s fakeSession) Environ() []string {
+	return s.env
+}
+
+func (s fakeSession) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestSessionSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		sess        fakeSession
+		serverASCII bool
+		ascii       bool
+		profile     termenv.Profile
+	}{
+		{"256 colors", fakeSession{term: "xterm-256color"}, false, false, termenv.ANSI256},
+		{"true color", fakeSession{term: "xterm", env: []string{"COLORTERM=truecolor"}}, false, false, termenv.TrueColor},
+		{"no color", fakeSession{term: "xterm-256color", env: []string{"NO_COLOR=1"}}, false, true, termenv.Ascii},
+		{"ascii server", fakeSession{term: "xterm-256color"}, true, true, termenv.Ascii},
+	}
+	for _, tt := range tests {
+		s := &server{opts: Options{Settings: settings.Settings{ASCII: tt.serverASCII}}}
+		st := s.settings(tt.sess)
+		if st.ASCII != tt.ascii || st.Renderer.ColorProfile() != tt.profile {
+			t.Errorf("%s: got ascii %t and profile %d, want %t and %d", tt.name, st.ASCII, st.Renderer.ColorProfile(), tt.ascii, tt.profile)
+		}
+	}
+
+	// one session's profile leaves another's alone
+	plain := (&server{}).settings(fakeSession{term: "dumb", env: []string{"NO_COLOR=1"}})
+	colored := (&server{}).settings(fakeSession{term: "xterm-256color"})
+	if plain.Renderer.ColorProfile() != termenv.Ascii || colored.Renderer.ColorProfile() != termenv.ANSI256 {
+		t.Error("sessions share a color profile")
+	}
+}
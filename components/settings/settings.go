@@ -0,0 +1,56 @@
+package settings
+
+import "github.com/charmbracelet/lipgloss"
+
+// Settings holds the display options shared by the app, board, and menu models.
+// The app model owns the settings and hands a copy to the board whenever they change.
+type Settings struct {
+	Colorblind bool   // draw cursor, selection, and errors with glyphs as well as color
+	Layout     Layout // board layout chosen by the user, LayoutAuto to follow the window size
+	ASCII      bool   // no color or box drawing characters, states are marked with plain characters
+
+	HighlightPeers  bool // highlight cells in the cursor cell's row, column, and box
+	HighlightDigits bool // highlight values and pencil marks matching the cursor cell's value
+
+	// draws the styles of a terminal that isn't ours, like an SSH session's, nil for our own
+	Renderer *lipgloss.Renderer
+}
+
+// returns a new style for the terminal the settings draw on, see Renderer
+func (s Settings) NewStyle() lipgloss.Style {
+	if s.Renderer == nil {
+		return lipgloss.NewStyle()
+	}
+	return s.Renderer.NewStyle()
+}
+
+// how much room each board cell takes up
+type Layout int
+
+const (
+	LayoutAuto    Layout = iota // largest layout that fits in the terminal
+	LayoutFull                  // 3 rows per cell with a padded 3x3 pencil grid
+	LayoutMedium                // 3 rows per cell with a packed 3x3 pencil grid
+	LayoutCompact               // 1 row per cell, pencils of the cursor cell shown in a side panel
+)
+
+var layoutNames = []string{"auto", "full", "medium", "compact"}
+
+func (l Layout) String() string {
+	return layoutNames[l]
+}
+
+// cycles auto -> full -> medium -> compact -> auto
+func (l Layout) Next() Layout {
+	return (l + 1) % Layout(len(layoutNames))
+}
+
+// converts a layout name to a Layout, ok is false if the name is unknown
+func ParseLayout(name string) (Layout, bool) {
+	for i, n := range layoutNames {
+		if n == name {
+			return Layout(i), true
+		}
+	}
+	return LayoutAuto, false
+}
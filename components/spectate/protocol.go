@@ -0,0 +1,107 @@
+/*
+Package spectate lets read-only spectators watch a game live. The player's
+game serves its board with a Server, and every spectator runs a program that
+mirrors it: the same puzzle, generated from the same options and seed, with the
+player's values, pencil marks, cursor and selected cells, and the game's clock.
+Spectators can't send
+anything back, they only watch.
+
+The protocol is one JSON object per line, every object has a type. Fields a
+message doesn't use are left out. Player to spectator:
+
+	{"type":"game","seed":42,"mode":2,"size":9,"killer":true,"variants":["diagonal"],"symmetry":"rotational"}
+	    the game being played, see board.GameOptions. Sent first, and again every
+	    time the player starts a new game
+	{"type":"board","contents":[{"row":0,"col":1,"val":5,"pencils":[2,3]}],"cursor":{"row":0,"col":1},"selected":[{"row":0,"col":1}],"elapsed":125,"won":false}
+	    the contents of every cell the player fills, see board.CellContents, their
+	    cursor and selected cells, the whole seconds the game has been played, or
+	    took once it is won, and whether they won. Sent whenever the board changes,
+	    a spectator that falls behind only gets the latest board
+
+Spectators send nothing, and messages of unknown types are ignored.
+*/
+package spectate
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/wire"
+)
+
+// message types, see the package comment
+const (
+	typeGame  = "game"
+	typeBoard = "board"
+)
+
+// a message of the spectate protocol, see the package comment for which fields each type uses
+type Message struct {
+	Type string `json:"type"`
+
+	board.GameOptions // the game of a game message
+
+	Contents []board.CellContents `json:"contents,omitempty"`
+	Cursor   *board.Cell          `json:"cursor,omitempty"`
+	Selected []board.Cell         `json:"selected,omitempty"`
+	Elapsed  int64                `json:"elapsed,omitempty"` // seconds
+	Won      bool                 `json:"won,omitempty"`
+}
+
+// a spectator's connection to the game it watches
+type Conn struct {
+	wire *wire.Conn
+}
+
+// blocks until the next message arrives
+func (c *Conn) Receive() (Message, error) {
+	var msg Message
+	err := c.wire.Receive(&msg)
+	return msg, err
+}
+
+func (c *Conn) Close() error {
+	return c.wire.Close()
+}
+
+// starts watching the game served at addr, returns the game being played
+func Watch(addr string) (*Conn, board.GameOptions, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, board.GameOptions{}, err
+	}
+	conn := &Conn{wire: wire.NewConn(c)}
+
+	msg, err := conn.Receive()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, err
+	}
+	if msg.Type != typeGame {
+		conn.Close()
+		return nil, board.GameOptions{}, fmt.Errorf("player sent %q, want %q", msg.Type, typeGame)
+	}
+	opts, err := msg.options()
+	if err != nil {
+		conn.Close()
+		return nil, board.GameOptions{}, err
+	}
+	return conn, opts, nil
+}
+
+// returns the game message for a game of opts
+func gameMessage(opts board.GameOptions) Message {
+	return Message{Type: typeGame, GameOptions: opts}
+}
+
+// returns the game options of a game message
+func (msg Message) options() (board.GameOptions, error) {
+	if msg.Seed == 0 {
+		return board.GameOptions{}, fmt.Errorf("player sent a game without a seed, we can't watch it")
+	}
+	if err := msg.GameOptions.Validate(); err != nil {
+		return board.GameOptions{}, fmt.Errorf("player sent a game we can't watch: %w", err)
+	}
+	return msg.GameOptions, nil
+}
@@ -0,0 +1,112 @@
+package spectate
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/wire"
+)
+
+/*
+Serves the player's game to spectators. The app model publishes its board after
+every update, and each spectator is sent the latest game and board by its own
+goroutine, so a slow spectator never holds up the game or the other spectators
+*/
+type Server struct {
+	mu      sync.Mutex
+	changed *sync.Cond // broadcast when the board changes or a spectator leaves
+	game    Message
+	board   Message
+	games   int // bumped when a new game starts
+	version int // bumped when the board changes, 0 until the first publish
+}
+
+func NewServer() *Server {
+	s := &Server{}
+	s.changed = sync.NewCond(&s.mu)
+	return s
+}
+
+// accepts spectators on l until it is closed
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.watch(wire.NewConn(c))
+	}
+}
+
+// sends the game being played and its board, if either changed since the last publish.
+// elapsed is the game's clock, it counts as a change once a second
+func (s *Server) Publish(opts board.GameOptions, contents []board.CellContents, cursor board.Cell, selected []board.Cell, elapsed time.Duration, won bool) {
+	game := gameMessage(opts)
+	b := Message{
+		Type:     typeBoard,
+		Contents: contents,
+		Cursor:   &cursor,
+		Selected: selected,
+		Elapsed:  int64(elapsed / time.Second),
+		Won:      won,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	newGame := !reflect.DeepEqual(game, s.game)
+	if !newGame && s.version > 0 && reflect.DeepEqual(b, s.board) {
+		return
+	}
+	if newGame {
+		s.game = game
+		s.games++
+	}
+	s.board = b
+	s.version++
+	s.changed.Broadcast()
+}
+
+// sends a spectator the latest game and board until they leave
+func (s *Server) watch(conn *wire.Conn) {
+	defer conn.Close()
+
+	// spectators send nothing, reading only tells us when they leave
+	gone := false
+	go func() {
+		var msg Message
+		for conn.Receive(&msg) == nil {
+		}
+		s.mu.Lock()
+		gone = true
+		s.changed.Broadcast()
+		s.mu.Unlock()
+	}()
+
+	sentGames, sentVersion := 0, 0
+	for {
+		s.mu.Lock()
+		for s.version == sentVersion && !gone {
+			s.changed.Wait()
+		}
+		if gone {
+			s.mu.Unlock()
+			return
+		}
+		game, b, games, version := s.game, s.board, s.games, s.version
+		s.mu.Unlock()
+
+		if games != sentGames {
+			if conn.Send(game) != nil {
+				return
+			}
+			sentGames = games
+		}
+		if conn.Send(b) != nil {
+			return
+		}
+		sentVersion = version
+	}
+}
@@ -0,0 +1,169 @@
+package spectate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const LEFT_COLOR = lipgloss.Color("#5C5C5C")
+
+// this is a tea.Msg type sent when the player starts a game, the app model starts watching it
+type GameStarted struct {
+	Options board.GameOptions
+}
+
+// this is a tea.Msg type sent when the player's board changes, the app model mirrors it
+type BoardUpdated struct {
+	Contents []board.CellContents
+	Cursor   board.Cell
+	Selected []board.Cell
+	Elapsed  time.Duration // how long the player has played the game, or took once won
+	Won      bool
+}
+
+// redraws the clock
+type tickMsg struct{}
+
+// the connection to the player broke or they sent something we can't watch
+type disconnectedMsg struct {
+	err error
+}
+
+/*
+Side panel of a spectator, showing the game's clock, and the spectator's side of
+the protocol. The model turns the player's messages into GameStarted and
+BoardUpdated messages for the app model. The player only sends the clock when the
+board changes, so the panel keeps it running in between. The zero Model is not
+watching and does nothing
+*/
+type Model struct {
+	conn     *Conn
+	settings settings.Settings
+	left     error // why we lost the player, nil while connected
+
+	elapsed  time.Duration // the clock as the player last sent it
+	received time.Time     // when it was sent
+	won      bool          // the clock has stopped
+}
+
+func NewModel(conn *Conn, s settings.Settings) Model {
+	return Model{conn: conn, settings: s}
+}
+
+// returns true if we are watching a game, even if we lost the player
+func (m Model) Watching() bool {
+	return m.conn != nil
+}
+
+// starts listening for the player's messages
+func (m Model) Init() tea.Cmd {
+	if !m.Watching() {
+		return nil
+	}
+	return tea.Batch(m.receive(), tick())
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
+
+// waits for the next message from the player
+func (m Model) receive() tea.Cmd {
+	conn := m.conn
+	return func() tea.Msg {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				return disconnectedMsg{err}
+			}
+			switch msg.Type {
+			case typeGame:
+				opts, err := msg.options()
+				if err != nil {
+					return disconnectedMsg{err}
+				}
+				return GameStarted{Options: opts}
+			case typeBoard:
+				if msg.Cursor == nil {
+					continue
+				}
+				return BoardUpdated{
+					Contents: msg.Contents,
+					Cursor:   *msg.Cursor,
+					Selected: msg.Selected,
+					Elapsed:  time.Duration(msg.Elapsed) * time.Second,
+					Won:      msg.Won,
+				}
+			}
+		}
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case GameStarted:
+		m.elapsed, m.received, m.won = 0, time.Now(), false
+		return m, m.receive()
+
+	case BoardUpdated:
+		m.elapsed, m.received, m.won = msg.Elapsed, time.Now(), msg.Won
+		return m, m.receive()
+
+	case tickMsg:
+		if m.left == nil {
+			return m, tick()
+		}
+
+	case disconnectedMsg:
+		m.left = msg.err
+		m.conn.Close()
+	}
+
+	return m, nil
+}
+
+// replaces the panel's display settings
+func (m *Model) SetSettings(s settings.Settings) {
+	m.settings = s
+}
+
+func (m Model) View() string {
+	if !m.Watching() {
+		return ""
+	}
+
+	if m.left != nil {
+		left := "player gone"
+		if !m.settings.ASCII {
+			left = m.settings.NewStyle().Foreground(LEFT_COLOR).Faint(true).Render(left)
+		}
+		return strings.Join([]string{"watching", left}, "\n")
+	}
+	return strings.Join([]string{"watching", formatClock(m.clock())}, "\n")
+}
+
+// returns the game's clock as it is now, running on from the last one the player sent
+func (m Model) clock() time.Duration {
+	if m.won || m.received.IsZero() {
+		return m.elapsed
+	}
+	return m.elapsed + time.Since(m.received)
+}
+
+// writes d as minutes and seconds, with hours in front once it has any
+func formatClock(d time.Duration) string {
+	secs := int(d / time.Second)
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
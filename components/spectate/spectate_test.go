@@ -0,0 +1,118 @@
+package spectate
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+)
+
+// how long a test waits for a message that should arrive
+const receiveTimeout = 5 * time.Second
+
+// serves spectators on the loopback interface, returns the server and its address
+func serve(t *testing.T) (*Server, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := NewServer()
+	go s.Serve(l)
+	return s, l.Addr().String()
+}
+
+func watch(t *testing.T, addr string) (*Conn, board.GameOptions) {
+	t.Helper()
+	conn, opts, err := Watch(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, opts
+}
+
+// returns the next message from the player, failing the test if none comes in time
+func receive(t *testing.T, conn *Conn) Message {
+	t.Helper()
+	received := make(chan Message, 1)
+	failed := make(chan error, 1)
+	go func() {
+		msg, err := conn.Receive()
+		if err != nil {
+			failed <- err
+			return
+		}
+		received <- msg
+	}()
+	select {
+	case msg := <-received:
+		return msg
+	case err := <-failed:
+		t.Fatal(err)
+	case <-time.After(receiveTimeout):
+		t.Fatal("no message from the player")
+	}
+	return Message{}
+}
+
+func TestWatch(t *testing.T) {
+	s, addr := serve(t)
+	opts := board.GameOptions{Seed: 42, Mode: 2, Size: 6, Killer: true, Symmetry: "rotational"}
+	cursor := board.Cell{Row: 1, Col: 2}
+	contents := []board.CellContents{{Cell: cursor, Val: 5, Pencils: []int8{2, 3}}}
+	s.Publish(opts, contents, cursor, []board.Cell{cursor}, 95*time.Second+500*time.Millisecond, false)
+
+	conn, got := watch(t, addr)
+	if !reflect.DeepEqual(got, opts) {
+		t.Fatalf("watching %+v, want %+v", got, opts)
+	}
+	msg := receive(t, conn)
+	want := Message{Type: typeBoard, Contents: contents, Cursor: &cursor, Selected: []board.Cell{cursor}, Elapsed: 95}
+	if !reflect.DeepEqual(msg, want) {
+		t.Fatalf("got %+v, want %+v", msg, want)
+	}
+
+	// the same board a moment later is not sent again, the next second is
+	s.Publish(opts, contents, cursor, []board.Cell{cursor}, 95*time.Second+900*time.Millisecond, false)
+	s.Publish(opts, contents, cursor, []board.Cell{cursor}, 96*time.Second, true)
+	if msg := receive(t, conn); msg.Elapsed != 96 || !msg.Won {
+		t.Fatalf("got %+v, want the won board at 96 seconds", msg)
+	}
+
+	// a new game is sent before its board
+	opts.Seed, opts.Killer = 43, false
+	s.Publish(opts, nil, board.Cell{}, nil, 0, false)
+	msg = receive(t, conn)
+	if got, err := msg.options(); msg.Type != typeGame || err != nil || !reflect.DeepEqual(got, opts) {
+		t.Fatalf("got %+v, want the new game %+v", msg, opts)
+	}
+	if msg := receive(t, conn); msg.Type != typeBoard || msg.Elapsed != 0 || msg.Won {
+		t.Fatalf("got %+v, want the new game's board", msg)
+	}
+}
+
+func TestWatchUnseeded(t *testing.T) {
+	s, addr := serve(t)
+	s.Publish(board.GameOptions{Size: 9}, nil, board.Cell{}, nil, 0, false)
+	if _, _, err := Watch(addr); err == nil {
+		t.Error("watched a game without a seed")
+	}
+}
+
+func TestClock(t *testing.T) {
+	m := Model{elapsed: 90 * time.Second, received: time.Now().Add(-15 * time.Second)}
+	if got := formatClock(m.clock()); got != "1:45" {
+		t.Errorf("running clock shows %s, want 1:45", got)
+	}
+	m.won = true
+	if got := formatClock(m.clock()); got != "1:30" {
+		t.Errorf("stopped clock shows %s, want 1:30", got)
+	}
+	if got := formatClock(2*time.Hour + 3*time.Minute + 4*time.Second); got != "2:03:04" {
+		t.Errorf("clock shows %s, want 2:03:04", got)
+	}
+}
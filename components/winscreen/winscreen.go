@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hisamafahri/coco"
@@ -20,6 +22,7 @@ type Model struct {
 	textColEndIdx   int
 
 	width, height int
+	settings      settings.Settings
 }
 
 type StopAnim struct{}
@@ -34,7 +37,7 @@ const (
 	bannerSpeed     = 1 // how many cols move per frame
 )
 
-func NewModel(w, h int) Model {
+func NewModel(w, h int, s settings.Settings) Model {
 	return Model{
 		animationState:  "",
 		sourceText:      getTextToDisplay(w),
@@ -43,6 +46,7 @@ func NewModel(w, h int) Model {
 		textColEndIdx:   2,
 		width:           w,
 		height:          h,
+		settings:        s,
 	}
 }
 
@@ -92,7 +96,7 @@ func (m *Model) updateAnim() {
 	for i := 0; i < len(m.sourceText); i++ {
 		for j := m.textColStartIdx; j < m.textColEndIdx; j++ {
 			line := string([]rune(m.sourceText[i])[j])
-			m.animationState += lipgloss.NewStyle().Foreground(m.color).Render(line)
+			m.animationState += m.settings.NewStyle().Foreground(m.color).Render(line)
 		}
 		m.animationState += "\n"
 	}
@@ -0,0 +1,53 @@
+// Package wire sends and receives JSON messages over a network connection, one
+// message per line. It is the transport of the race and coop protocols
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// how long sending a message may take before we give up on the other side
+const writeTimeout = 5 * time.Second
+
+// a connection sending and receiving messages a line at a time
+type Conn struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func NewConn(c net.Conn) *Conn {
+	return &Conn{conn: c, scanner: bufio.NewScanner(c)}
+}
+
+// writes msg as a line of JSON
+func (c *Conn) Send(msg any) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = c.conn.Write(append(line, '\n'))
+	return err
+}
+
+// blocks until the next message arrives and decodes it into msg
+func (c *Conn) Receive(msg any) error {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("connection closed")
+	}
+	if err := json.Unmarshal(c.scanner.Bytes(), msg); err != nil {
+		return fmt.Errorf("bad message: %w", err)
+	}
+	return nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alex-Merrill/sudoku-tui/components/batch"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+)
+
+// a subcommand, its arguments and what it does, for the usage text
+type command struct {
+	name, args, about string
+}
+
+// the subcommands in the order the usage lists them, playing a game on our own takes none
+var commands = []command{
+	{"host", "<mode>", "host a race, the other player joins with your address"},
+	{"join", "<addr>", "join a race at host:port, the host picks the game"},
+	{"serve", "[mode]", "host the game over SSH, mode defaults to medium"},
+	{"watch", "<addr>", "watch a game played with --spectate at host:port"},
+	{"replay", "<file>", "play back a game recorded with --record"},
+	{"solve", "[file...]", "solve and grade puzzles from files or stdin"},
+	{"generate", "", "print generated puzzles"},
+	{"export", "-o <file> [file...]", "lay puzzles from files or stdin out on printable .pdf or .svg pages"},
+}
+
+// returns how c is run, like sudoku-tui join [flags] <addr>
+func (c command) synopsis() string {
+	synopsis := "sudoku-tui " + c.name + " [flags]"
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	return synopsis
+}
+
+// returns the subcommand called name, or false if there is none
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// the values of the flags, each subcommand only registers the ones it takes
+type flags struct {
+	game     board.GameOptions
+	settings settings.Settings
+	layout   string
+	puzzle   string
+	variant  string
+	symmetry string
+
+	port     int
+	coop     bool
+	spectate int
+	record   string
+	cast     string
+
+	idle     time.Duration
+	sessions int
+	data     string
+
+	jobs       int
+	json       bool
+	limit      int
+	count      int
+	difficulty string
+	givens     string
+	format     string
+
+	out        string
+	perPage    int
+	candidates bool
+	solutions  bool
+}
+
+// returns the flag set of c, with the flags it takes registered on f
+func (f *flags) flagSet(c command) *flag.FlagSet {
+	name := strings.TrimSpace("sudoku-tui " + c.name)
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		if c.name == "" {
+			fmt.Fprintln(out, "sudoku-tui [flags] <mode>")
+			fmt.Fprintln(out, "sudoku-tui [flags] --puzzle <file>")
+			for _, c := range commands {
+				fmt.Fprintf(out, "%s - %s\n", c.synopsis(), c.about)
+			}
+			fmt.Fprintln(out, "Run sudoku-tui <command> -h for the flags of a command")
+		} else {
+			fmt.Fprintf(out, "%s - %s\n", c.synopsis(), c.about)
+		}
+		if strings.Contains(c.args, "mode") || c.name == "" {
+			fmt.Fprintln(out, "  <mode> - easy, medium, hard, expert")
+		}
+		fmt.Fprintln(out, "\nflags:")
+		fs.PrintDefaults()
+	}
+
+	switch c.name {
+	case "":
+		f.gameFlags(fs)
+		fs.StringVar(&f.puzzle, "puzzle", "", "play the puzzle `file` instead of generating a puzzle, see README")
+		f.shareFlags(fs)
+		f.displayFlags(fs)
+	case "host":
+		f.gameFlags(fs)
+		fs.IntVar(&f.port, "port", 7777, "`port` to host the race or coop game on")
+		fs.BoolVar(&f.coop, "coop", false, "solve one board together instead of racing")
+		f.shareFlags(fs)
+		f.displayFlags(fs)
+	case "join":
+		fs.BoolVar(&f.coop, "coop", false, "join a coop game instead of a race")
+		f.shareFlags(fs)
+		f.displayFlags(fs)
+	case "serve":
+		f.gameFlags(fs)
+		fs.IntVar(&f.port, "port", 2222, "`port` to serve on")
+		fs.DurationVar(&f.idle, "idle", 15*time.Minute, "close sessions idle this long, 0 for never")
+		fs.IntVar(&f.sessions, "sessions", 16, "sessions allowed at once, 0 for no limit")
+		fs.StringVar(&f.data, "data", "", "`dir` the host key and players' saves are kept in (default sudoku-tui in your config directory)")
+		f.displayFlags(fs)
+	case "watch":
+		f.displayFlags(fs)
+	case "replay":
+		fs.StringVar(&f.cast, "cast", "", "write the replay to asciinema recording `file` instead of playing it")
+		f.displayFlags(fs)
+	case "solve":
+		fs.IntVar(&f.jobs, "j", 0, "puzzles solved at once (default one per CPU)")
+		fs.BoolVar(&f.json, "json", false, "print a JSON object per puzzle")
+		fs.IntVar(&f.limit, "limit", 2, "stop counting solutions at `n`")
+	case "generate":
+		fs.IntVar(&f.game.Size, "size", 9, "board `size`: "+sizeList())
+		fs.IntVar(&f.count, "n", 1, "puzzles to print, 0 for until interrupted")
+		fs.StringVar(&f.difficulty, "difficulty", "", "keep puzzles of `grade`: "+strings.Join(board.Grades, ", ")+", or a band like medium-hard")
+		fs.StringVar(&f.givens, "givens", "", "keep puzzles with `n` given cells, or a range like 24-28")
+		fs.StringVar(&f.symmetry, "symmetry", "none", "lay the givens out with `symmetry`: "+strings.Join(board.Symmetries, ", "))
+		fs.StringVar(&f.format, "format", "line", "print puzzles as `format`: "+strings.Join(batch.Formats, ", "))
+		fs.IntVar(&f.jobs, "j", 0, "puzzles generated at once (default one per CPU)")
+	case "export":
+		fs.StringVar(&f.out, "o", "", "the .pdf or .svg `file` to write")
+		fs.IntVar(&f.perPage, "per-page", 1, "puzzles on a page: 1, 2, 4 or 6")
+		fs.BoolVar(&f.candidates, "candidates", false, "pencil the candidates the givens leave into the empty cells")
+		fs.BoolVar(&f.solutions, "solutions", false, "add pages with the solutions")
+	}
+	return fs
+}
+
+// registers the flags that pick the puzzle to generate
+func (f *flags) gameFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&f.game.Killer, "killer", false, "play killer sudoku, cages must add up to their sum")
+	fs.BoolVar(&f.game.Samurai, "samurai", false, "play samurai sudoku, five 9x9 grids sharing their corner boxes")
+	fs.IntVar(&f.game.Size, "size", 9, "board `size`: "+sizeList())
+	fs.StringVar(&f.variant, "variant", "", "add variant `rules`, comma separated: "+strings.Join(board.VariantNames(), ", "))
+	fs.StringVar(&f.symmetry, "symmetry", "none", "lay the givens out with `symmetry`: "+strings.Join(board.Symmetries, ", "))
+}
+
+// registers the flags that share our game with others
+func (f *flags) shareFlags(fs *flag.FlagSet) {
+	fs.IntVar(&f.spectate, "spectate", 0, "let spectators watch your game on `port`")
+	fs.StringVar(&f.record, "record", "", "record your moves to replay `file`, see README")
+}
+
+// registers the flags that change how the board is drawn
+func (f *flags) displayFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&f.settings.Colorblind, "colorblind", false, "mark cursor, selection, and errors with glyphs as well as color")
+	fs.StringVar(&f.layout, "layout", "auto", "`layout`: auto, full, medium or compact, auto picks by window size")
+	fs.BoolVar(&f.settings.ASCII, "ascii", false, "ascii borders and no color, also enabled by setting NO_COLOR")
+}
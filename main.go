@@ -1,12 +1,32 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	model "github.com/Alex-Merrill/sudoku-tui/components"
+	"github.com/Alex-Merrill/sudoku-tui/components/batch"
+	"github.com/Alex-Merrill/sudoku-tui/components/board"
+	"github.com/Alex-Merrill/sudoku-tui/components/coop"
+	"github.com/Alex-Merrill/sudoku-tui/components/export"
+	"github.com/Alex-Merrill/sudoku-tui/components/race"
+	"github.com/Alex-Merrill/sudoku-tui/components/replay"
+	"github.com/Alex-Merrill/sudoku-tui/components/serve"
+	"github.com/Alex-Merrill/sudoku-tui/components/settings"
+	"github.com/Alex-Merrill/sudoku-tui/components/spectate"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 // sudoku generator library is broken for true medium difficulty
@@ -20,7 +40,50 @@ const (
 )
 
 func main() {
-	var mode int
+	// host and join play a race against another player, see the race package,
+	// or with --coop solve a shared board with other players, see the coop package.
+	// serve hosts the game over SSH, see the serve package, watch spectates a game
+	// served with --spectate, see the spectate package, and replay plays back a game
+	// recorded with --record, see the replay package. solve and generate solve and
+	// generate puzzles in bulk without a terminal, see the batch package, and export
+	// lays puzzles out on printable pages, see the export package
+	var c command
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if found, ok := findCommand(args[0]); ok {
+			c, args = found, args[1:]
+		}
+	}
+	command := c.name
+
+	// each command only takes its own flags, so the flag package turns away the rest
+	f := flags{game: board.GameOptions{Size: 9}}
+	fs := f.flagSet(c)
+	fs.Parse(args)
+
+	// solve, generate and export only need the puzzles, they don't start the game
+	if command == "solve" {
+		solvePuzzles(fs, batch.SolveOptions{Jobs: f.jobs, JSON: f.json, Limit: f.limit})
+		return
+	}
+	if command == "export" {
+		exportPuzzles(fs, f.out, export.Options{PerPage: f.perPage, Candidates: f.candidates, Solutions: f.solutions})
+		return
+	}
+	if command == "generate" {
+		if fs.NArg() != 0 {
+			usage(fs)
+		}
+		generatePuzzles(fs, f.game.Size, f.count, f.difficulty, f.givens, f.symmetry, f.format, f.jobs)
+		return
+	}
+
+	// the edits of a coop game are the server's
+	if f.record != "" && f.coop {
+		usage(fs)
+	}
+
+	opts, s := f.game, f.settings
 	modeMap := map[string]int{
 		"easy":   LEVEL_EASY,
 		"medium": LEVEL_MEDIUM,
@@ -28,26 +91,438 @@ func main() {
 		"expert": LEVEL_EXPERT,
 	}
 
-	// incorrect amount of args
-	if len(os.Args) < 2 || len(os.Args) > 2 {
-		fmt.Println(printArgHelp())
-		os.Exit(0)
+	// the host or player picks the game, we only need its address, or the replay file
+	if command == "join" || command == "watch" || command == "replay" {
+		if fs.NArg() != 1 {
+			usage(fs)
+		}
+	} else if f.puzzle != "" { // a puzzle file doesn't need a mode
+		// spectators are sent the game as a seed, a puzzle file can't be sent that way
+		if fs.NArg() != 0 || f.spectate != 0 {
+			usage(fs)
+		}
+		puzzle, err := board.LoadPuzzle(f.puzzle)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		opts.Puzzle = &puzzle
+	} else if command == "serve" && fs.NArg() == 0 { // sessions play medium unless told otherwise
+		opts.Mode = LEVEL_MEDIUM
+	} else if fs.NArg() != 1 { // incorrect amount of args
+		usage(fs)
 	} else { // handle mode checking
-		if _, ok := modeMap[os.Args[1]]; !ok {
-			fmt.Println(printArgHelp())
-			os.Exit(0)
+		if _, ok := modeMap[fs.Arg(0)]; !ok {
+			usage(fs)
+		}
+		opts.Mode = modeMap[fs.Arg(0)]
+	}
+
+	if f.variant != "" {
+		for _, name := range strings.Split(f.variant, ",") {
+			if !board.IsVariant(name) {
+				usage(fs)
+			}
+			opts.Variants = append(opts.Variants, name)
 		}
 	}
 
-	mode = modeMap[os.Args[1]]
+	if !board.IsSize(opts.Size) {
+		usage(fs)
+	}
+
+	// puzzle files lay out their own givens
+	if f.symmetry != "" && f.symmetry != "none" {
+		if !board.IsSymmetry(f.symmetry) || f.puzzle != "" {
+			usage(fs)
+		}
+		opts.Symmetry = f.symmetry
+	}
+
+	// samurai puzzles are five classic 9x9 grids
+	if opts.Samurai && (opts.Killer || opts.Size != 9 || len(opts.Variants) > 0) {
+		usage(fs)
+	}
+
+	var ok bool
+	if s.Layout, ok = settings.ParseLayout(f.layout); !ok {
+		usage(fs)
+	}
+
+	// plain rendering for dumb terminals, also used when NO_COLOR is set (https://no-color.org/)
+	if termenv.EnvNoColor() {
+		s.ASCII = true
+	}
+	if s.ASCII {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	if command == "serve" {
+		serveSSH(opts, s, f.port, f.data, f.idle, f.sessions)
+		return
+	}
+
+	if command == "replay" && f.cast != "" {
+		exportCast(fs.Arg(0), f.cast, s)
+		return
+	}
+	if command == "replay" {
+		replayGame(fs.Arg(0), s)
+		return
+	}
+
+	// spectators generate the puzzle from its seed, and so do replays
+	if (f.spectate != 0 || f.record != "") && opts.Puzzle == nil && opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
 
-	p := tea.NewProgram(model.NewModel(mode), tea.WithAltScreen())
+	var m model.Model
+	switch {
+	case command == "host" && f.coop:
+		m = hostCoop(opts, s, f.port)
+	case command == "join" && f.coop:
+		m = joinCoop(fs.Arg(0), s)
+	case command == "host":
+		m = hostRace(opts, s, f.port)
+	case command == "join":
+		m = joinRace(fs.Arg(0), s)
+	case command == "watch":
+		m = watchGame(fs.Arg(0), s)
+	default:
+		m = started(model.NewModel(opts, s))
+	}
+
+	if f.spectate != 0 {
+		m.SetSpectators(serveSpectators(f.spectate))
+	}
+
+	if f.record != "" {
+		recorder := startRecording(f.record, f.puzzle)
+		m.SetRecorder(recorder)
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				fmt.Println("recording:", err)
+			}
+		}()
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	if err := p.Start(); err != nil {
 		panic(err)
 	}
 }
 
-func printArgHelp() string {
-	return `sudoku-tui <mode>
-               <mode> - easy, medium, hard, expert`
+// creates the replay file at path, with the puzzle file at puzzlePath if one is played
+func startRecording(path, puzzlePath string) *replay.Recorder {
+	var puzzle []byte
+	if puzzlePath != "" {
+		var err error
+		if puzzle, err = os.ReadFile(puzzlePath); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	recorder, err := replay.NewRecorder(path, string(puzzle))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return recorder
+}
+
+// writes the replay file at path to an asciinema recording at castPath
+func exportCast(path, castPath string, s settings.Settings) {
+	events, err := replay.Load(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// the recording is played in someone else's terminal, so we can't go by ours
+	if !s.ASCII {
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+
+	f, err := os.Create(castPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = replay.WriteCast(f, events, s)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// solves the puzzles in the files named by the arguments of fs, or stdin, and prints the results
+func solvePuzzles(fs *flag.FlagSet, opts batch.SolveOptions) {
+	if opts.Jobs < 0 || opts.Limit < 1 {
+		usage(fs)
+	}
+	if err := batch.Solve(os.Stdout, fs.Args(), opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// writes count generated puzzles to stdout, or keeps going until interrupted for a count of 0.
+// difficulty is a grade or a band of them like medium-hard, givens a number or a range like 24-28
+func generatePuzzles(fs *flag.FlagSet, size, count int, difficulty, givens, symmetry, format string, jobs int) {
+	opts := batch.GenerateOptions{Count: count, Size: size, Symmetry: symmetry, Format: format, Jobs: jobs}
+	ok := count >= 0 && jobs >= 0 && board.IsSize(size) && board.IsSymmetry(symmetry) && batch.IsFormat(format)
+	if difficulty != "" {
+		opts.Grades = gradeBand(difficulty)
+		ok = ok && opts.Grades != nil
+	}
+	if givens != "" {
+		first, last, found := strings.Cut(givens, "-")
+		if !found {
+			last = first
+		}
+		var err1, err2 error
+		opts.MinGivens, err1 = strconv.Atoi(first)
+		opts.MaxGivens, err2 = strconv.Atoi(last)
+		ok = ok && err1 == nil && err2 == nil && opts.MinGivens > 0 && opts.MinGivens <= opts.MaxGivens && opts.MaxGivens <= size*size
+	}
+	if !ok {
+		usage(fs)
+	}
+
+	// ctrl+c stops the workers, the puzzles written so far are kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := batch.Generate(ctx, os.Stdout, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+/*
+lays the puzzles in the files named by the arguments of fs, or stdin, out on pages and writes them to
+outPath, a PDF document or SVG images by its extension. SVG has no pages, so
+every page after the first goes to a file of its own: pages.svg, pages-2.svg...
+*/
+func exportPuzzles(fs *flag.FlagSet, outPath string, opts export.Options) {
+	ext := filepath.Ext(outPath)
+	if (ext != ".pdf" && ext != ".svg") || !export.IsLayout(opts.PerPage) {
+		usage(fs)
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	var puzzles []board.Puzzle
+	for _, path := range paths {
+		batch.ReadPuzzles(path, func(source string, puzzle board.Puzzle, err error) {
+			if err != nil {
+				fmt.Printf("%s: %s\n", source, err)
+				os.Exit(1)
+			}
+			puzzles = append(puzzles, puzzle)
+		})
+	}
+	pages, err := export.Pages(puzzles, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	write := func(path string, w func(io.Writer) error) {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = w(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if ext == ".pdf" {
+		write(outPath, func(w io.Writer) error { return export.WritePDF(w, pages) })
+		return
+	}
+	for i, page := range pages {
+		path := outPath
+		if i > 0 {
+			path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(outPath, ext), i+1, ext)
+		}
+		write(path, func(w io.Writer) error { return export.WriteSVG(w, page) })
+	}
+}
+
+// returns the grades from first to last of a band like medium-hard, or the one grade
+// named, nil if they aren't grades
+func gradeBand(band string) []string {
+	first, last, found := strings.Cut(band, "-")
+	if !found {
+		last = first
+	}
+	from, to := -1, -1
+	for i, grade := range board.Grades {
+		if grade == first {
+			from = i
+		}
+		if grade == last {
+			to = i
+		}
+	}
+	if from == -1 || to < from {
+		return nil
+	}
+	return board.Grades[from : to+1]
+}
+
+// plays back the replay file at path
+func replayGame(path string, s settings.Settings) {
+	events, err := replay.Load(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	m, err := replay.NewModel(events, s)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if err := p.Start(); err != nil {
+		panic(err)
+	}
+}
+
+// waits for a player to join a race on port, then sets up the race
+func hostRace(opts board.GameOptions, s settings.Settings, port int) model.Model {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer l.Close()
+
+	fmt.Printf("Waiting for a player to join on port %d...\n", port)
+	conn, opts, err := race.Host(l, opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return started(model.NewRaceModel(opts, s, conn, true))
+}
+
+// joins the race hosted at addr and sets it up with the host's game
+func joinRace(addr string, s settings.Settings) model.Model {
+	conn, opts, err := race.Join(addr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return started(model.NewRaceModel(opts, s, conn, false))
+}
+
+// serves a coop game on port and joins it as the first player, others can join at any time
+func hostCoop(opts board.GameOptions, s settings.Settings, port int) model.Model {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	server, err := coop.NewServer(opts)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	go server.Serve(l)
+
+	return joinCoop(net.JoinHostPort("localhost", strconv.Itoa(port)), s)
+}
+
+// joins the coop game served at addr
+func joinCoop(addr string, s settings.Settings) model.Model {
+	conn, opts, player, err := coop.Join(addr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return started(model.NewCoopModel(opts, s, conn, player))
+}
+
+// serves our game to spectators on port
+func serveSpectators(port int) *spectate.Server {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	spectators := spectate.NewServer()
+	go spectators.Serve(l)
+	return spectators
+}
+
+// watches the game served to spectators at addr
+func watchGame(addr string, s settings.Settings) model.Model {
+	conn, opts, err := spectate.Watch(addr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return started(model.NewSpectatorModel(opts, s, conn))
+}
+
+// returns m, or exits with err if the game couldn't be started
+func started(m model.Model, err error) model.Model {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return m
+}
+
+// serves the game over SSH on port until the server fails
+func serveSSH(opts board.GameOptions, s settings.Settings, port int, dir string, idle time.Duration, maxSessions int) {
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		dir = filepath.Join(config, "sudoku-tui")
+	}
+
+	addr := ":" + strconv.Itoa(port)
+	fmt.Printf("Serving on port %d, play with: ssh -p %d <host>\n", port, port)
+	err := serve.ListenAndServe(addr, serve.Options{
+		Game:        opts,
+		Settings:    s,
+		Dir:         dir,
+		IdleTimeout: idle,
+		MaxSessions: maxSessions,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// prints the usage of the command fs parsed and exits, for arguments its flags can't check
+func usage(fs *flag.FlagSet) {
+	fs.Usage()
+	os.Exit(0)
+}
+
+func sizeList() string {
+	var sizes []string
+	for _, size := range board.Sizes() {
+		sizes = append(sizes, strconv.Itoa(size))
+	}
+	return strings.Join(sizes, ", ")
 }
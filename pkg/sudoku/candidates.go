@@ -0,0 +1,65 @@
+package sudoku
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// a set of values a cell can hold, bit v-1 for value v. The zero value is empty
+type Candidates uint16
+
+// returns the set of every value from 1 to size
+func AllCandidates(size int) Candidates {
+	return 1<<size - 1
+}
+
+// returns true if v is in the set
+func (c Candidates) Has(v int) bool {
+	return v >= 1 && v <= 16 && c&(1<<(v-1)) != 0
+}
+
+// returns the set with v added, values out of range are left out
+func (c Candidates) Add(v int) Candidates {
+	if v < 1 || v > 16 {
+		return c
+	}
+	return c | 1<<(v-1)
+}
+
+// returns the set with v removed
+func (c Candidates) Remove(v int) Candidates {
+	if v < 1 || v > 16 {
+		return c
+	}
+	return c &^ (1 << (v - 1))
+}
+
+// returns the number of values in the set
+func (c Candidates) Count() int {
+	return bits.OnesCount16(uint16(c))
+}
+
+// calls fn with every value in the set, smallest first, without allocating
+func (c Candidates) Iterate(fn func(v int)) {
+	for rest := c; rest != 0; rest &= rest - 1 {
+		fn(bits.TrailingZeros16(uint16(rest)) + 1)
+	}
+}
+
+// returns the values in the set, smallest first
+func (c Candidates) Values() []int {
+	values := make([]int, 0, c.Count())
+	c.Iterate(func(v int) {
+		values = append(values, v)
+	})
+	return values
+}
+
+// writes the values in the set like FormatValue, smallest first
+func (c Candidates) String() string {
+	var b strings.Builder
+	for _, v := range c.Values() {
+		b.WriteString(FormatValue(v))
+	}
+	return b.String()
+}
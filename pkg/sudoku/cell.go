@@ -0,0 +1,26 @@
+package sudoku
+
+import "fmt"
+
+// a cell of a grid, counting from 0
+type Cell struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// writes the cell as r<row>c<col> counting from 1, the way puzzle files do
+func (c Cell) String() string {
+	return fmt.Sprintf("r%dc%d", c.Row+1, c.Col+1)
+}
+
+// parses a cell written as r<row>c<col> counting from 1, see Cell.String
+func ParseCell(s string) (Cell, error) {
+	var row, col int
+	if n, err := fmt.Sscanf(s, "r%dc%d", &row, &col); err != nil || n != 2 || fmt.Sprintf("r%dc%d", row, col) != s {
+		return Cell{}, fmt.Errorf("bad cell %q, want r<row>c<col>", s)
+	}
+	if row < 1 || col < 1 {
+		return Cell{}, fmt.Errorf("cell %q is off the grid, rows and columns count from 1", s)
+	}
+	return Cell{row - 1, col - 1}, nil
+}
@@ -0,0 +1,45 @@
+package sudoku_test
+
+import (
+	"fmt"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+func Example() {
+	g, err := sudoku.Parse("7..518...4..7.9..3.8.42.76..35..6.2...6...5..2...51.76.481.529.92..8.....57..4...")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	grade, _ := g.Grade()
+	fmt.Println(grade, g.Empty(), "empty cells")
+	fmt.Println("r1c2 could be", g.Candidates(sudoku.Cell{Row: 0, Col: 1}))
+
+	solution, err := g.Solve()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(solution)
+	// Output:
+	// easy 45 empty cells
+	// r1c2 could be 69
+	// 763 518 942
+	// 412 769 853
+	// 589 423 761
+	//
+	// 835 976 124
+	// 176 342 589
+	// 294 851 376
+	//
+	// 648 135 297
+	// 921 687 435
+	// 357 294 618
+}
+
+func ExampleGrid_Validate() {
+	g, _ := sudoku.Parse("1... .2.. ..3. 1..4")
+	fmt.Println(g.Validate())
+	// Output: grid breaks the rules: r1c1 and r4c1 both hold 1
+}
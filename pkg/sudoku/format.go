@@ -0,0 +1,80 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/*
+Parses a grid written row by row, one character a cell: 1 to 9 and A to G for
+values, . or 0 for empty cells. Whitespace is skipped, so both the one line
+form of Format and the rows of String parse, and the size is told from the
+number of cells. Values are only checked to fit the size, see Validate
+*/
+func Parse(s string) (Grid, error) {
+	var cells []int8
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		v, ok := ParseValue(string(r))
+		if !ok {
+			return Grid{}, fmt.Errorf("bad character %q in grid, want 1-9, A-G, . or 0", r)
+		}
+		cells = append(cells, int8(v))
+	}
+
+	size := 0
+	for _, s := range sizes {
+		if s*s == len(cells) {
+			size = s
+		}
+	}
+	if size == 0 {
+		lengths := make([]string, len(sizes))
+		for i, s := range sizes {
+			lengths[i] = fmt.Sprint(s * s)
+		}
+		return Grid{}, fmt.Errorf("grid has %d cells, want %s", len(cells), strings.Join(lengths, ", "))
+	}
+	for i, v := range cells {
+		if int(v) > size {
+			return Grid{}, fmt.Errorf("%s holds %s, too big for a %dx%d grid",
+				Cell{i / size, i % size}, FormatValue(int(v)), size, size)
+		}
+	}
+	return Grid{size: size, cells: cells}, nil
+}
+
+// writes the grid on one line row by row, . for empty cells. Parse reads it back
+func (g Grid) Format() string {
+	var b strings.Builder
+	for _, v := range g.cells {
+		b.WriteString(FormatValue(int(v)))
+	}
+	return b.String()
+}
+
+// writes the grid one row to a line with a space between boxes, for reading.
+// Parse reads it back
+func (g Grid) String() string {
+	if g.size == 0 {
+		return ""
+	}
+	boxRows, boxCols := BoxShape(g.size)
+	var b strings.Builder
+	for i := 0; i < g.size; i++ {
+		if i > 0 && i%boxRows == 0 {
+			b.WriteByte('\n')
+		}
+		for j := 0; j < g.size; j++ {
+			if j > 0 && j%boxCols == 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(FormatValue(int(g.cells[i*g.size+j])))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package sudoku_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// grids that parse have to write themselves back the same both ways, and those small
+// enough to solve quickly have to solve to a full, valid grid that keeps their values
+func FuzzParse(f *testing.F) {
+	for _, p := range puzzles {
+		f.Add(p.line)
+		f.Add(p.solution)
+	}
+	f.Add(strings.Repeat(".", 36))
+	f.Add(".23.....1...4...")
+	f.Add("21 ..\n34 2.\n\n.. 3.\n.. 1.\n")
+	f.Add("1A" + strings.Repeat("0", 254))
+
+	f.Fuzz(func(t *testing.T, s string) {
+		g, err := sudoku.Parse(s)
+		if err != nil {
+			return
+		}
+		for _, written := range []string{g.Format(), g.String()} {
+			again, err := sudoku.Parse(written)
+			if err != nil {
+				t.Fatalf("%q wrote %q which doesn't parse: %v", s, written, err)
+			}
+			if again.Format() != g.Format() {
+				t.Fatalf("%q wrote %q which parses as %q", s, written, again.Format())
+			}
+		}
+
+		valid := g.Validate() == nil
+		if g.Size() > 9 {
+			return
+		}
+		solution, err := g.Solve()
+		if errors.Is(err, sudoku.ErrNoSolution) {
+			return
+		}
+		if !valid {
+			t.Fatalf("%q breaks the rules but solves to %q", s, solution.Format())
+		}
+		if solution.Empty() != 0 || solution.Validate() != nil {
+			t.Fatalf("%q solves to %q, which isn't a full valid grid", s, solution.Format())
+		}
+		for row := 0; row < g.Size(); row++ {
+			for col := 0; col < g.Size(); col++ {
+				c := sudoku.Cell{Row: row, Col: col}
+				if v := g.Get(c); v != 0 && solution.Get(c) != v {
+					t.Fatalf("%q solves to %q, which changes %s", s, solution.Format(), c)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package sudoku
+
+// how hard a puzzle is for a person to solve, see Grid.Grade
+type Grade string
+
+const (
+	Easy   Grade = "easy"
+	Medium Grade = "medium"
+	Hard   Grade = "hard"
+	Expert Grade = "expert"
+)
+
+// grades from easiest to hardest
+var Grades = []Grade{Easy, Medium, Hard, Expert}
+
+// values a 9x9 puzzle may take to search once singles run out and still be graded
+// Hard, other sizes scale it by their number of cells, see SearchGrade
+const hardMaxNodes = 60
+
+/*
+Grades the puzzle by how a person would solve it. An easy puzzle falls to
+naked singles, cells with one candidate left, and a medium one also needs
+hidden singles, values with one place left in a row, column or box. When
+singles run out the rest is guessing, and the values the solver tries from
+there tell hard from expert. Returns ErrNoSolution for a grid with no
+solution, a grid with many is graded by the first the solver finds
+*/
+func (g Grid) Grade() (Grade, error) {
+	s := newSolver(g.size, 1)
+	candidates := s.initialCandidates(g.cells)
+	if candidates == nil {
+		return "", ErrNoSolution
+	}
+	cells := g.Clone().cells
+	houses := houses(g.size)
+
+	singles := Easy
+	for {
+		i, v, ok := NakedSingle(candidates)
+		if !ok {
+			i, v, ok = HiddenSingle(candidates, houses)
+			if ok {
+				singles = Medium
+			}
+		}
+		if !ok {
+			break
+		}
+		cells[i] = int8(v)
+		s.place(candidates, i, int8(v))
+	}
+
+	s.search(cells, candidates)
+	if s.solutions == 0 {
+		return "", ErrNoSolution
+	}
+	return SearchGrade(singles, s.nodes, len(cells)), nil
+}
+
+/*
+Returns the grade of a puzzle of cells cells that singles filled in as far as they
+go, Easy if naked singles were enough and Medium if it took hidden singles, and a
+backtracking search then solved by placing nodes values. Grid.Grade grades with it,
+and so can solvers of rules this package doesn't know, with NakedSingle and HiddenSingle
+*/
+func SearchGrade(singles Grade, nodes, cells int) Grade {
+	switch {
+	case nodes == 0:
+		return singles
+	case nodes <= hardMaxNodes*cells/81:
+		return Hard
+	}
+	return Expert
+}
+
+// returns a cell with one candidate left and its value. Cells are indexes into
+// candidates, and filled cells have no candidates
+func NakedSingle(candidates []Candidates) (int, int, bool) {
+	for i, c := range candidates {
+		if c.Count() == 1 {
+			return i, c.Values()[0], true
+		}
+	}
+	return 0, 0, false
+}
+
+// returns a value that fits only one cell of a house, and that cell. Houses are the
+// indexes into candidates of cells that can't repeat a value, like rows, columns and
+// boxes, and filled cells have no candidates
+func HiddenSingle(candidates []Candidates, houses [][]int) (int, int, bool) {
+	for _, house := range houses {
+		var once, twice Candidates
+		for _, i := range house {
+			twice |= once & candidates[i]
+			once |= candidates[i]
+		}
+		single := once &^ twice
+		if single == 0 {
+			continue
+		}
+		for _, i := range house {
+			if candidates[i]&single != 0 {
+				return i, (candidates[i] & single).Values()[0], true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,169 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+a square grid of values, 0 for an empty cell. Grids share their cells when
+copied, use Clone for a copy that can be changed on its own. The zero value
+is a grid of size 0, use New or Parse to get one to play on
+*/
+type Grid struct {
+	size  int
+	cells []int8 // row by row
+}
+
+// returns an empty grid of size, see Sizes
+func New(size int) (Grid, error) {
+	if !IsSize(size) {
+		return Grid{}, fmt.Errorf("can't make a %dx%d grid, want one of %s", size, size, sizeNames())
+	}
+	return Grid{size: size, cells: make([]int8, size*size)}, nil
+}
+
+// returns the number of rows and columns of the grid
+func (g Grid) Size() int {
+	return g.size
+}
+
+// returns true if cell is on the grid
+func (g Grid) Contains(cell Cell) bool {
+	return cell.Row >= 0 && cell.Row < g.size && cell.Col >= 0 && cell.Col < g.size
+}
+
+// returns the value of cell, 0 if it's empty or off the grid
+func (g Grid) Get(cell Cell) int {
+	if !g.Contains(cell) {
+		return 0
+	}
+	return int(g.cells[cell.Row*g.size+cell.Col])
+}
+
+// sets the value of cell, 0 to empty it. Values are only checked to be in range,
+// see Validate for the rules
+func (g *Grid) Set(cell Cell, v int) error {
+	if !g.Contains(cell) {
+		return fmt.Errorf("%s is off the %dx%d grid", cell, g.size, g.size)
+	}
+	if v < 0 || v > g.size {
+		return fmt.Errorf("can't put %d in %s, values go from 1 to %d", v, cell, g.size)
+	}
+	g.cells[cell.Row*g.size+cell.Col] = int8(v)
+	return nil
+}
+
+// returns a copy of the grid that shares nothing with it
+func (g Grid) Clone() Grid {
+	return Grid{size: g.size, cells: append([]int8(nil), g.cells...)}
+}
+
+// returns the number of empty cells
+func (g Grid) Empty() int {
+	empty := 0
+	for _, v := range g.cells {
+		if v == 0 {
+			empty++
+		}
+	}
+	return empty
+}
+
+// returns the cells that share a row, column or box with cell, not counting cell itself
+func (g Grid) Peers(cell Cell) []Cell {
+	if !g.Contains(cell) {
+		return nil
+	}
+	var peers []Cell
+	for _, idx := range peerIndexes(g.size)[cell.Row*g.size+cell.Col] {
+		peers = append(peers, Cell{idx / g.size, idx % g.size})
+	}
+	return peers
+}
+
+// returns the values cell could hold without repeating a value of its peers,
+// nothing if it isn't empty
+func (g Grid) Candidates(cell Cell) Candidates {
+	if !g.Contains(cell) || g.Get(cell) != 0 {
+		return 0
+	}
+	c := AllCandidates(g.size)
+	for _, idx := range peerIndexes(g.size)[cell.Row*g.size+cell.Col] {
+		if v := g.cells[idx]; v != 0 {
+			c = c.Remove(int(v))
+		}
+	}
+	return c
+}
+
+// returns the houses of a grid of size, its rows and columns in turn and then its
+// boxes, each as cell indexes
+func houses(size int) [][]int {
+	var houses [][]int
+	for i := 0; i < size; i++ {
+		row, col := make([]int, size), make([]int, size)
+		for j := 0; j < size; j++ {
+			row[j], col[j] = i*size+j, j*size+i
+		}
+		houses = append(houses, row, col)
+	}
+	boxRows, boxCols := BoxShape(size)
+	for r := 0; r < size; r += boxRows {
+		for c := 0; c < size; c += boxCols {
+			var box []int
+			for i := r; i < r+boxRows; i++ {
+				for j := c; j < c+boxCols; j++ {
+					box = append(box, i*size+j)
+				}
+			}
+			houses = append(houses, box)
+		}
+	}
+	return houses
+}
+
+// peers of every cell as indexes for each of Sizes, read only so grids can be used
+// from many goroutines
+var peerTable = func() map[int][][]int {
+	table := make(map[int][][]int)
+	for _, size := range sizes {
+		seen := make([]map[int]bool, size*size)
+		for i := range seen {
+			seen[i] = make(map[int]bool)
+		}
+		for _, house := range houses(size) {
+			for _, a := range house {
+				for _, b := range house {
+					if a != b {
+						seen[a][b] = true
+					}
+				}
+			}
+		}
+		peers := make([][]int, size*size)
+		for i := range peers {
+			for j := 0; j < size*size; j++ {
+				if seen[i][j] {
+					peers[i] = append(peers[i], j)
+				}
+			}
+		}
+		table[size] = peers
+	}
+	return table
+}()
+
+// returns the peers of every cell of a grid of size as indexes, in order
+func peerIndexes(size int) [][]int {
+	return peerTable[size]
+}
+
+// returns Sizes written as 4x4, 6x6 and so on for error messages
+func sizeNames() string {
+	names := make([]string, len(sizes))
+	for i, size := range sizes {
+		names[i] = fmt.Sprintf("%dx%d", size, size)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,159 @@
+package sudoku_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		size int
+		line string
+	}{
+		{"1234 3412 2143 4321", 4, "1234341221434321"},
+		{"0000000000000000", 4, "................"},
+		{"123...\n\t456...\n......\n......\n......\n......\n", 6, "123...456..." + strings.Repeat(".", 24)},
+		{strings.Repeat(".", 80) + "9", 9, strings.Repeat(".", 80) + "9"},
+		{"c" + strings.Repeat(".", 143), 12, "C" + strings.Repeat(".", 143)},
+		{"G" + strings.Repeat(".", 255), 16, "G" + strings.Repeat(".", 255)},
+	}
+	for _, tt := range tests {
+		g, err := sudoku.Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if g.Size() != tt.size || g.Format() != tt.line {
+			t.Errorf("Parse(%q) = %d, %q, want %d, %q", tt.in, g.Size(), g.Format(), tt.size, tt.line)
+		}
+		// both forms read back to the same grid
+		for _, s := range []string{g.Format(), g.String()} {
+			if again := mustParse(t, s); again.Format() != g.Format() {
+				t.Errorf("%q read back as %q", s, again.Format())
+			}
+		}
+	}
+
+	bad := []string{
+		"",
+		"123",
+		strings.Repeat(".", 15) + "x",
+		strings.Repeat(".", 15) + "5", // too big for 4x4
+		strings.Repeat(".", 82),
+	}
+	for _, s := range bad {
+		if _, err := sudoku.Parse(s); err == nil {
+			t.Errorf("Parse(%q) is ok, want an error", s)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	g := mustParse(t, "21..342...3...1.")
+	want := "21 ..\n34 2.\n\n.. 3.\n.. 1.\n"
+	if got := g.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	if _, err := sudoku.New(5); err == nil {
+		t.Error("New(5) is ok, want an error")
+	}
+	g, err := sudoku.New(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Empty() != 36 {
+		t.Errorf("new grid has %d empty cells, want 36", g.Empty())
+	}
+
+	cell := sudoku.Cell{Row: 1, Col: 4}
+	clone := g.Clone()
+	if err := g.Set(cell, 6); err != nil {
+		t.Fatal(err)
+	}
+	if g.Get(cell) != 6 || g.Empty() != 35 {
+		t.Errorf("Get = %d with %d empty cells after Set(%v, 6)", g.Get(cell), g.Empty(), cell)
+	}
+	if clone.Get(cell) != 0 {
+		t.Error("Set changed a clone")
+	}
+	for _, v := range []int{-1, 7} {
+		if err := g.Set(cell, v); err == nil {
+			t.Errorf("Set(%v, %d) is ok, want an error", cell, v)
+		}
+	}
+	if err := g.Set(sudoku.Cell{Row: 6, Col: 0}, 1); err == nil {
+		t.Error("Set off the grid is ok, want an error")
+	}
+	if g.Get(sudoku.Cell{Row: -1, Col: 0}) != 0 {
+		t.Error("Get off the grid isn't 0")
+	}
+}
+
+func TestPeers(t *testing.T) {
+	g, _ := sudoku.New(6)
+	peers := g.Peers(sudoku.Cell{Row: 0, Col: 0})
+	// 5 in the row, 5 in the column and the 2 cells of the 2x3 box in the next row
+	if len(peers) != 12 {
+		t.Errorf("r1c1 of a 6x6 grid has %d peers, want 12: %v", len(peers), peers)
+	}
+	for _, p := range peers {
+		if p == (sudoku.Cell{Row: 0, Col: 0}) {
+			t.Error("a cell is its own peer")
+		}
+	}
+	if peers := g.Peers(sudoku.Cell{Row: 6, Col: 6}); peers != nil {
+		t.Errorf("a cell off the grid has peers %v", peers)
+	}
+}
+
+func TestGridCandidates(t *testing.T) {
+	g := mustParse(t, "21..342...3...1.")
+	tests := []struct {
+		cell sudoku.Cell
+		want []int
+	}{
+		{sudoku.Cell{Row: 0, Col: 2}, []int{4}},
+		{sudoku.Cell{Row: 3, Col: 0}, []int{4}},
+		{sudoku.Cell{Row: 2, Col: 3}, []int{2, 4}},
+		{sudoku.Cell{Row: 0, Col: 0}, []int{}}, // filled
+	}
+	for _, tt := range tests {
+		if got := g.Candidates(tt.cell).Values(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Candidates(%v) = %v, want %v", tt.cell, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, p := range puzzles {
+		if err := mustParse(t, p.line).Validate(); err != nil {
+			t.Errorf("%s: %v", p.line, err)
+		}
+	}
+
+	// 1 repeats in the first column and box, 2 in the last column and box
+	g := mustParse(t, "1... 1... ...2 ...2")
+	err := g.Validate()
+	var verr *sudoku.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v, want a *ValidationError", err)
+	}
+	want := []sudoku.Conflict{
+		{A: sudoku.Cell{Row: 0, Col: 0}, B: sudoku.Cell{Row: 1, Col: 0}, Value: 1},
+		{A: sudoku.Cell{Row: 2, Col: 3}, B: sudoku.Cell{Row: 3, Col: 3}, Value: 2},
+	}
+	if !reflect.DeepEqual(verr.Conflicts, want) {
+		t.Errorf("conflicts %v, want %v", verr.Conflicts, want)
+	}
+	if got := err.Error(); !strings.Contains(got, "r1c1 and r2c1 both hold 1") {
+		t.Errorf("error %q doesn't name the cells", got)
+	}
+}
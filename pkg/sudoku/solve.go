@@ -0,0 +1,127 @@
+package sudoku
+
+import "errors"
+
+var (
+	// returned when a grid has no solution
+	ErrNoSolution = errors.New("grid has no solution")
+	// returned when a grid has more than one solution
+	ErrNotUnique = errors.New("grid has more than one solution")
+)
+
+/*
+backtracking solver. Every cell keeps the values it can still hold, placing a
+value removes it from its peers, and we always fill the empty cell with the
+fewest candidates next, trying its values from smallest to largest. The game
+solves and grades its classic puzzles with it too
+*/
+type solver struct {
+	size  int
+	peers [][]int
+	limit int // stop after finding this many solutions
+
+	nodes     int // values placed while searching
+	solutions int
+	first     []int8
+}
+
+func newSolver(size, limit int) *solver {
+	return &solver{size: size, peers: peerIndexes(size), limit: limit}
+}
+
+// returns the candidates of every cell of cells, nil if a value repeats
+func (s *solver) initialCandidates(cells []int8) []Candidates {
+	candidates := make([]Candidates, len(cells))
+	for i := range candidates {
+		candidates[i] = AllCandidates(s.size)
+	}
+	for i, v := range cells {
+		if v == 0 {
+			continue
+		}
+		if !candidates[i].Has(int(v)) {
+			return nil
+		}
+		s.place(candidates, i, v)
+	}
+	return candidates
+}
+
+// clears the candidates of cell i and removes v from its peers
+func (s *solver) place(candidates []Candidates, i int, v int8) {
+	candidates[i] = 0
+	for _, j := range s.peers[i] {
+		candidates[j] = candidates[j].Remove(int(v))
+	}
+}
+
+func (s *solver) search(cells []int8, candidates []Candidates) {
+	best, bestCount := -1, s.size+1
+	for i, v := range cells {
+		if v != 0 {
+			continue
+		}
+		count := candidates[i].Count()
+		if count == 0 { // dead end
+			return
+		}
+		if count < bestCount {
+			best, bestCount = i, count
+		}
+	}
+
+	// no empty cells left, we have a solution
+	if best == -1 {
+		s.solutions++
+		if s.first == nil {
+			s.first = append([]int8(nil), cells...)
+		}
+		return
+	}
+
+	next := make([]Candidates, len(candidates))
+	for _, v := range candidates[best].Values() {
+		s.nodes++
+		cells[best] = int8(v)
+		copy(next, candidates)
+		s.place(next, best, int8(v))
+		s.search(cells, next)
+		cells[best] = 0
+		if s.solutions >= s.limit {
+			return
+		}
+	}
+}
+
+// counts the solutions of the grid, stopping once limit are found. Grids that
+// break the rules have none
+func (g Grid) CountSolutions(limit int) int {
+	s := newSolver(g.size, limit)
+	candidates := s.initialCandidates(g.cells)
+	if candidates == nil || limit < 1 {
+		return 0
+	}
+	s.search(g.Clone().cells, candidates)
+	return s.solutions
+}
+
+/*
+Returns the solution of the grid, or ErrNoSolution if it has none, which is
+also the case when it breaks the rules. A grid with more than one solution
+returns its first and ErrNotUnique, puzzles should have exactly one
+*/
+func (g Grid) Solve() (Grid, error) {
+	s := newSolver(g.size, 2)
+	candidates := s.initialCandidates(g.cells)
+	if candidates == nil {
+		return Grid{}, ErrNoSolution
+	}
+	s.search(g.Clone().cells, candidates)
+	switch s.solutions {
+	case 0:
+		return Grid{}, ErrNoSolution
+	case 1:
+		return Grid{size: g.size, cells: s.first}, nil
+	}
+	return Grid{size: g.size, cells: s.first}, ErrNotUnique
+}
@@ -0,0 +1,86 @@
+package sudoku_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+// puzzles with one solution each, by grade
+var puzzles = []struct {
+	line     string
+	solution string
+	grade    sudoku.Grade
+}{
+	{
+		"7..518...4..7.9..3.8.42.76..35..6.2...6...5..2...51.76.481.529.92..8.....57..4...",
+		"763518942412769853589423761835976124176342589294851376648135297921687435357294618",
+		sudoku.Easy,
+	},
+	{
+		"8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4..",
+		"812753649943682175675491283154237896369845721287169534521974368438526917796318452",
+		sudoku.Expert,
+	},
+	{"21..342...3...1.", "2143342112344312", sudoku.Easy},
+}
+
+func mustParse(t *testing.T, s string) sudoku.Grid {
+	t.Helper()
+	g, err := sudoku.Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return g
+}
+
+func TestSolve(t *testing.T) {
+	for _, p := range puzzles {
+		g := mustParse(t, p.line)
+		solution, err := g.Solve()
+		if err != nil || solution.Format() != p.solution {
+			t.Errorf("Solve(%s) = %s, %v, want %s", p.line, solution.Format(), err, p.solution)
+		}
+		if g.Format() != p.line {
+			t.Errorf("Solve changed the grid to %s", g.Format())
+		}
+		if n := g.CountSolutions(10); n != 1 {
+			t.Errorf("%s has %d solutions, want 1", p.line, n)
+		}
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := []struct {
+		line  string
+		err   error
+		count int // solutions counting up to 5
+	}{
+		{"11..............", sudoku.ErrNoSolution, 0}, // breaks the rules
+		{".23.....1...4...", sudoku.ErrNoSolution, 0}, // r1c1 has no candidates left
+		{"................", sudoku.ErrNotUnique, 5},  // an empty grid has 288
+		{"..34341...434321", sudoku.ErrNotUnique, 2},  // 1 and 2 can swap places in r1 and r3
+		{"1234341221434321", nil, 1},                  // already solved
+	}
+	for _, tt := range tests {
+		g := mustParse(t, tt.line)
+		if _, err := g.Solve(); !errors.Is(err, tt.err) {
+			t.Errorf("Solve(%s) error %v, want %v", tt.line, err, tt.err)
+		}
+		if n := g.CountSolutions(5); n != tt.count {
+			t.Errorf("CountSolutions(%s) = %d, want %d", tt.line, n, tt.count)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	for _, p := range puzzles {
+		if grade, err := mustParse(t, p.line).Grade(); err != nil || grade != p.grade {
+			t.Errorf("Grade(%s) = %s, %v, want %s", p.line, grade, err, p.grade)
+		}
+	}
+	if _, err := mustParse(t, "11..............").Grade(); !errors.Is(err, sudoku.ErrNoSolution) {
+		t.Errorf("grading a grid that breaks the rules returned %v, want ErrNoSolution", err)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Package sudoku is the classic sudoku engine of sudoku-tui as a library: grids
+of 4x4 up to 16x16, parsing and formatting them, checking them against the
+rules, solving and grading. It has no dependencies outside the standard
+library, so bots and other programs can use it without the terminal UI.
+
+	g, err := sudoku.Parse("8..........36......7..9.2...5...7.......457.....1...3...1....68..85...1..9....4..")
+	if err != nil {
+		return err
+	}
+	solution, err := g.Solve()
+	grade, err := g.Grade()
+
+Cells count from 0, and values go from 1 to the grid's size with 0 for an
+empty cell. Values above 9 are written as letters, A for 10 up to G for 16.
+The rules are the classic ones: no value repeats in a row, column or box.
+Boxes are as square as they can be, see BoxShape.
+
+sudoku-tui plays killer, jigsaw, samurai and variant puzzles on top of these
+rules, those stay in the game for now.
+*/
+package sudoku
+
+import (
+	"math"
+	"strings"
+)
+
+// grid sizes, boxes are 2x2, 2x3, 3x3, 3x4 and 4x4
+var sizes = []int{4, 6, 9, 12, 16}
+
+// returns the grid sizes from smallest to largest, a copy that callers may change
+func Sizes() []int {
+	return append([]int(nil), sizes...)
+}
+
+// returns true if size is one of Sizes
+func IsSize(size int) bool {
+	for _, s := range sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the rows and cols of a box on a grid of size. Boxes are as square as
+// possible and at least as wide as they are tall, i.e. 2x3 for 6x6 and 3x4 for 12x12
+func BoxShape(size int) (int, int) {
+	rows := int(math.Sqrt(float64(size)))
+	for size%rows != 0 {
+		rows--
+	}
+	return rows, size / rows
+}
+
+// characters for values, values above 9 are written as letters
+const digits = "123456789ABCDEFG"
+
+// returns the character for value v, "." for an empty cell or a value out of range
+func FormatValue(v int) string {
+	if v < 1 || v > len(digits) {
+		return "."
+	}
+	return digits[v-1 : v]
+}
+
+// returns the value of a character, letters in either case, 0 for . and 0, and
+// false if s isn't one
+func ParseValue(s string) (int, bool) {
+	if len(s) != 1 {
+		return 0, false
+	}
+	if s == "." || s == "0" {
+		return 0, true
+	}
+	idx := strings.Index(digits, strings.ToUpper(s))
+	if idx == -1 {
+		return 0, false
+	}
+	return idx + 1, true
+}
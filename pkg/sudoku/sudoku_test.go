@@ -0,0 +1,97 @@
+package sudoku_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Alex-Merrill/sudoku-tui/pkg/sudoku"
+)
+
+func TestBoxShape(t *testing.T) {
+	tests := []struct{ size, rows, cols int }{
+		{4, 2, 2}, {6, 2, 3}, {9, 3, 3}, {12, 3, 4}, {16, 4, 4},
+	}
+	for _, tt := range tests {
+		if rows, cols := sudoku.BoxShape(tt.size); rows != tt.rows || cols != tt.cols {
+			t.Errorf("BoxShape(%d) = %d, %d, want %d, %d", tt.size, rows, cols, tt.rows, tt.cols)
+		}
+	}
+}
+
+func TestSizes(t *testing.T) {
+	sizes := sudoku.Sizes()
+	sizes[0] = 5
+	if sudoku.IsSize(5) || !sudoku.IsSize(4) || sudoku.Sizes()[0] != 4 {
+		t.Error("changing the slice Sizes returned changed the sizes")
+	}
+}
+
+func TestValues(t *testing.T) {
+	for v := 1; v <= 16; v++ {
+		s := sudoku.FormatValue(v)
+		if got, ok := sudoku.ParseValue(s); !ok || got != v {
+			t.Errorf("ParseValue(%q) = %d, %v, want %d", s, got, ok, v)
+		}
+	}
+	for _, s := range []string{".", "0"} {
+		if got, ok := sudoku.ParseValue(s); !ok || got != 0 {
+			t.Errorf("ParseValue(%q) = %d, %v, want 0", s, got, ok)
+		}
+	}
+	for _, s := range []string{"", "H", "12", "23", "..", "00", "-"} {
+		if _, ok := sudoku.ParseValue(s); ok {
+			t.Errorf("ParseValue(%q) is ok, want an error", s)
+		}
+	}
+	if s := sudoku.FormatValue(0); s != "." {
+		t.Errorf("FormatValue(0) = %q, want .", s)
+	}
+}
+
+func TestCell(t *testing.T) {
+	cell := sudoku.Cell{Row: 2, Col: 10}
+	if s := cell.String(); s != "r3c11" {
+		t.Errorf("String() = %q, want r3c11", s)
+	}
+	if got, err := sudoku.ParseCell("r3c11"); err != nil || got != cell {
+		t.Errorf("ParseCell(r3c11) = %v, %v, want %v", got, err, cell)
+	}
+	for _, s := range []string{"", "r0c1", "r1", "c1r1", "r1c1x", "r01c1"} {
+		if _, err := sudoku.ParseCell(s); err == nil {
+			t.Errorf("ParseCell(%q) is ok, want an error", s)
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	var c sudoku.Candidates
+	c = c.Add(3).Add(12).Add(1).Add(3)
+	if c.Count() != 3 || !c.Has(1) || !c.Has(12) || c.Has(2) {
+		t.Errorf("%v after adding 3, 12, 1 and 3 again", c.Values())
+	}
+	if got := c.String(); got != "13C" {
+		t.Errorf("String() = %q, want 13C", got)
+	}
+	c = c.Remove(3).Remove(5)
+	if got := fmt.Sprint(c.Values()); got != "[1 12]" {
+		t.Errorf("Values() = %s after removing 3 and 5, want [1 12]", got)
+	}
+	if c.Has(0) || c.Has(17) {
+		t.Error("Has is true for a value out of range")
+	}
+	if c.Add(0).Add(17).Remove(-1) != c {
+		t.Error("adding or removing a value out of range changed the set")
+	}
+	sum := 0
+	c.Iterate(func(v int) { sum += v })
+	if sum != 13 {
+		t.Errorf("Iterate visited values adding up to %d, want 13", sum)
+	}
+
+	for _, size := range sudoku.Sizes() {
+		all := sudoku.AllCandidates(size)
+		if all.Count() != size || !all.Has(size) || all.Has(size+1) {
+			t.Errorf("AllCandidates(%d) = %v", size, all.Values())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+)
+
+// two cells sharing a row, column or box that hold the same value
+type Conflict struct {
+	A, B  Cell
+	Value int
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s and %s both hold %s", c.A, c.B, FormatValue(c.Value))
+}
+
+// returned by Validate when values repeat, every pair of cells they repeat in
+type ValidationError struct {
+	Conflicts []Conflict
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		names[i] = c.String()
+	}
+	return "grid breaks the rules: " + strings.Join(names, ", ")
+}
+
+/*
+Checks that no value repeats in a row, column or box. Returns a
+*ValidationError with every conflict, A before B in reading order and the
+pairs sorted by A then B, or nil if the grid follows the rules. A valid grid
+can still have no solution, see Solve
+*/
+func (g Grid) Validate() error {
+	var conflicts []Conflict
+	peers := peerIndexes(g.size)
+	for i, v := range g.cells {
+		if v == 0 {
+			continue
+		}
+		for _, j := range peers[i] {
+			if j > i && g.cells[j] == v {
+				conflicts = append(conflicts, Conflict{
+					A:     Cell{i / g.size, i % g.size},
+					B:     Cell{j / g.size, j % g.size},
+					Value: int(v),
+				})
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ValidationError{Conflicts: conflicts}
+	}
+	return nil
+}